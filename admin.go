@@ -0,0 +1,486 @@
+package influxlogger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/InfluxCommunity/influxdb3-go/v2/influxdb3"
+	"github.com/hadi77ir/go-logging"
+)
+
+// ErrAdminUnsupported is returned by the admin helpers when the backend of
+// the writer has no API to manage its database through.
+var ErrAdminUnsupported = errors.New("backend doesn't support administration")
+
+// admin manages the database a backend writes to.
+type admin interface {
+	ensureRetention(ctx context.Context, retention time.Duration) error
+	installRollup(ctx context.Context, source, target, destination string, tags []string) error
+}
+
+// admin returns the admin of the writer's backend.
+func (w *LogWriter) admin() (admin, error) {
+	switch b := w.backend.(type) {
+	case *clientBackend:
+		return b.admin, nil
+	case *influxDB2Backend:
+		return b.admin, nil
+	case *httpBackend:
+		if b.admin != nil {
+			return b.admin, nil
+		}
+	}
+	return nil, ErrAdminUnsupported
+}
+
+// EnsureRetention creates the database the writer writes to if it doesn't
+// exist, keeping data for retention, or zero to keep it forever. The
+// retention of an existing database is adjusted if it differs. It is meant
+// to be called on startup, and needs a token or user allowed to manage
+// databases.
+//
+// On InfluxDB 1.x, the retention policy of the connection string is
+// adjusted, or the default one. On InfluxDB 3, the retention can't be read
+// back, so it is always set, and a zero retention only applies to new
+// databases.
+func (w *LogWriter) EnsureRetention(ctx context.Context, retention time.Duration) error {
+	if retention < 0 {
+		return errors.New("invalid retention")
+	}
+	a, err := w.admin()
+	if err != nil {
+		return err
+	}
+	return a.ensureRetention(ctx, retention)
+}
+
+// rollupTags returns the tags hourly counts are grouped by: severity,
+// appname and the host tag, under the name the writer writes it with.
+func (w *LogWriter) rollupTags() []string {
+	tags := []string{"severity", "appname"}
+	for _, host := range []string{"host", "hostname"} {
+		if _, ok := w.tags[logging.InfoLevel][host]; ok {
+			return append(tags, host)
+		}
+	}
+	return tags
+}
+
+// InstallHourlyRollup installs a server-side job counting the entries of
+// every hour into the target measurement, per severity, appname and host,
+// so that trend dashboards don't depend on keeping raw logs. The counts
+// are written to destination, a retention policy on InfluxDB 1.x or a
+// bucket on InfluxDB 2.x, so they can be kept longer than raw logs; if it
+// is empty, they are written next to raw logs. An existing job for target
+// is replaced. It needs a token or user allowed to manage continuous
+// queries or tasks.
+//
+// On InfluxDB 1.x, the job is a continuous query, and on InfluxDB 2.x a
+// task. InfluxDB 3 has no built-in continuous aggregation, so there it
+// installs nothing and returns ErrAdminUnsupported.
+func (w *LogWriter) InstallHourlyRollup(ctx context.Context, target, destination string) error {
+	if target == "" || target == w.measurement {
+		return errors.New("invalid rollup measurement")
+	}
+	a, err := w.admin()
+	if err != nil {
+		return err
+	}
+	return a.installRollup(ctx, w.measurement, target, destination, w.rollupTags())
+}
+
+// rollupName is the name of the job rolling up into target.
+func rollupName(target string) string {
+	return "rollup_" + target
+}
+
+// adminClient sends the requests of an admin.
+type adminClient struct {
+	client *http.Client
+	// base is the URL the paths of the API are relative to
+	base   url.URL
+	header http.Header
+}
+
+// do sends a request with body encoded as JSON, unless it is nil, and
+// decodes the response into out, unless it is nil. Responses with a status
+// not in accept fail with a *WriteError.
+func (c *adminClient) do(ctx context.Context, method, path string, query url.Values, body, out any, accept ...int) error {
+	u := c.base
+	u.Path = strings.TrimSuffix(u.Path, "/") + path
+	u.RawQuery = query.Encode()
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), reader)
+	if err != nil {
+		return err
+	}
+	req.Header = c.header.Clone()
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+	}()
+	if !slices.Contains(accept, resp.StatusCode) {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		return &WriteError{
+			StatusCode: resp.StatusCode,
+			Body:       data,
+			Err:        fmt.Errorf("%s %s failed: %s: %s", method, path, resp.Status, bytes.TrimSpace(data)),
+		}
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// influxDB1Admin manages a database of InfluxDB 1.x through InfluxQL.
+type influxDB1Admin struct {
+	adminClient
+	database        string
+	retentionPolicy string
+}
+
+// influxQLResponse is the response of the /query endpoint.
+type influxQLResponse struct {
+	Results []struct {
+		Series []struct {
+			Name    string   `json:"name"`
+			Columns []string `json:"columns"`
+			Values  [][]any  `json:"values"`
+		} `json:"series"`
+		Error string `json:"error"`
+	} `json:"results"`
+	Error string `json:"error"`
+}
+
+// query runs an InfluxQL statement.
+func (a *influxDB1Admin) query(ctx context.Context, statement string) (*influxQLResponse, error) {
+	var resp influxQLResponse
+	query := url.Values{"q": {statement}}
+	if err := a.do(ctx, http.MethodPost, "/query", query, nil, &resp, http.StatusOK); err != nil {
+		return nil, err
+	}
+	if resp.Error != "" {
+		return nil, errors.New(resp.Error)
+	}
+	for _, result := range resp.Results {
+		if result.Error != "" {
+			return nil, errors.New(result.Error)
+		}
+	}
+	return &resp, nil
+}
+
+func (a *influxDB1Admin) ensureRetention(ctx context.Context, retention time.Duration) error {
+	// creating an existing database changes nothing
+	if _, err := a.query(ctx, "CREATE DATABASE "+quoteIdent(a.database)); err != nil {
+		return err
+	}
+	resp, err := a.query(ctx, "SHOW RETENTION POLICIES ON "+quoteIdent(a.database))
+	if err != nil {
+		return err
+	}
+	name, current, err := a.findRetentionPolicy(resp)
+	if err != nil {
+		return err
+	}
+	if current == retention {
+		return nil
+	}
+	_, err = a.query(ctx, fmt.Sprintf("ALTER RETENTION POLICY %s ON %s DURATION %s",
+		quoteIdent(name), quoteIdent(a.database), influxQLDuration(retention)))
+	return err
+}
+
+func (a *influxDB1Admin) installRollup(ctx context.Context, source, target, destination string, tags []string) error {
+	name := rollupName(target)
+	resp, err := a.query(ctx, "SHOW CONTINUOUS QUERIES")
+	if err != nil {
+		return err
+	}
+	if hasContinuousQuery(resp, a.database, name) {
+		_, err := a.query(ctx, fmt.Sprintf("DROP CONTINUOUS QUERY %s ON %s", quoteIdent(name), quoteIdent(a.database)))
+		if err != nil {
+			return err
+		}
+	}
+	into, from := quoteIdent(target), quoteIdent(source)
+	if a.retentionPolicy != "" {
+		from = quoteIdent(a.retentionPolicy) + "." + from
+	}
+	if destination == "" {
+		destination = a.retentionPolicy
+	}
+	if destination != "" {
+		into = quoteIdent(destination) + "." + into
+	}
+	groupBy := make([]string, len(tags))
+	for i, tag := range tags {
+		groupBy[i] = quoteIdent(tag)
+	}
+	_, err = a.query(ctx, fmt.Sprintf(
+		`CREATE CONTINUOUS QUERY %s ON %s BEGIN SELECT count("message") AS "count" INTO %s FROM %s GROUP BY time(1h), %s END`,
+		quoteIdent(name), quoteIdent(a.database), into, from, strings.Join(groupBy, ", ")))
+	return err
+}
+
+// hasContinuousQuery reports whether the result of SHOW CONTINUOUS QUERIES
+// lists the query name on database.
+func hasContinuousQuery(resp *influxQLResponse, database, name string) bool {
+	for _, result := range resp.Results {
+		for _, series := range result.Series {
+			if series.Name != database {
+				continue
+			}
+			for _, row := range series.Values {
+				if len(row) > 0 && row[0] == name {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// findRetentionPolicy returns the name and duration of the retention policy
+// written to, from the result of SHOW RETENTION POLICIES.
+func (a *influxDB1Admin) findRetentionPolicy(resp *influxQLResponse) (string, time.Duration, error) {
+	for _, result := range resp.Results {
+		for _, series := range result.Series {
+			columns := make(map[string]int, len(series.Columns))
+			for i, column := range series.Columns {
+				columns[column] = i
+			}
+			for _, row := range series.Values {
+				name, _ := row[columns["name"]].(string)
+				isDefault, _ := row[columns["default"]].(bool)
+				if name != a.retentionPolicy && (a.retentionPolicy != "" || !isDefault) {
+					continue
+				}
+				duration, _ := row[columns["duration"]].(string)
+				d, err := time.ParseDuration(duration)
+				return name, d, err
+			}
+		}
+	}
+	return "", 0, fmt.Errorf("retention policy not found on database %s", a.database)
+}
+
+// quoteIdent quotes an InfluxQL identifier.
+func quoteIdent(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// influxQLDuration renders d as an InfluxQL duration literal, where INF
+// stands for keeping data forever.
+func influxQLDuration(d time.Duration) string {
+	if d == 0 {
+		return "INF"
+	}
+	return fmt.Sprintf("%ds", int64(d/time.Second))
+}
+
+// influxDB2Admin manages a bucket of InfluxDB 2.x through its API.
+type influxDB2Admin struct {
+	adminClient
+	org    string
+	bucket string
+}
+
+type retentionRule struct {
+	Type         string `json:"type"`
+	EverySeconds int64  `json:"everySeconds"`
+}
+
+type bucketConfig struct {
+	ID             string          `json:"id,omitempty"`
+	OrgID          string          `json:"orgID,omitempty"`
+	Name           string          `json:"name,omitempty"`
+	RetentionRules []retentionRule `json:"retentionRules"`
+}
+
+// retentionRules returns the rules keeping data for retention, where no
+// rules keep it forever.
+func retentionRules(retention time.Duration) []retentionRule {
+	if retention == 0 {
+		return []retentionRule{}
+	}
+	return []retentionRule{{Type: "expire", EverySeconds: int64(retention / time.Second)}}
+}
+
+// orgID looks up the ID of the organization.
+func (a *influxDB2Admin) orgID(ctx context.Context) (string, error) {
+	var resp struct {
+		Orgs []struct {
+			ID string `json:"id"`
+		} `json:"orgs"`
+	}
+	err := a.do(ctx, http.MethodGet, "/api/v2/orgs", url.Values{"org": {a.org}}, nil, &resp, http.StatusOK)
+	if err != nil {
+		return "", err
+	}
+	if len(resp.Orgs) == 0 {
+		return "", fmt.Errorf("organization %s not found", a.org)
+	}
+	return resp.Orgs[0].ID, nil
+}
+
+func (a *influxDB2Admin) ensureRetention(ctx context.Context, retention time.Duration) error {
+	orgID, err := a.orgID(ctx)
+	if err != nil {
+		return err
+	}
+	var resp struct {
+		Buckets []bucketConfig `json:"buckets"`
+	}
+	query := url.Values{"orgID": {orgID}, "name": {a.bucket}}
+	// a missing bucket is answered with 404 by some versions
+	err = a.do(ctx, http.MethodGet, "/api/v2/buckets", query, nil, &resp, http.StatusOK, http.StatusNotFound)
+	if err != nil {
+		return err
+	}
+	if len(resp.Buckets) == 0 {
+		created := bucketConfig{OrgID: orgID, Name: a.bucket, RetentionRules: retentionRules(retention)}
+		return a.do(ctx, http.MethodPost, "/api/v2/buckets", nil, created, nil, http.StatusCreated)
+	}
+	current := resp.Buckets[0]
+	var currentRetention time.Duration
+	for _, rule := range current.RetentionRules {
+		if rule.Type == "expire" {
+			currentRetention = time.Duration(rule.EverySeconds) * time.Second
+		}
+	}
+	if currentRetention == retention {
+		return nil
+	}
+	update := bucketConfig{RetentionRules: retentionRules(retention)}
+	return a.do(ctx, http.MethodPatch, "/api/v2/buckets/"+url.PathEscape(current.ID), nil, update, nil, http.StatusOK)
+}
+
+// rollupFlux is the Flux script of the task rolling up source into target
+// of the destination bucket, grouped by tags.
+func (a *influxDB2Admin) rollupFlux(name, source, target, destination string, tags []string) string {
+	columns := make([]string, len(tags))
+	for i, tag := range tags {
+		columns[i] = strconv.Quote(tag)
+	}
+	return fmt.Sprintf(`option task = {name: %s, every: 1h}
+
+from(bucket: %s)
+    |> range(start: -task.every)
+    |> filter(fn: (r) => r._measurement == %s and r._field == "message")
+    |> group(columns: [%s])
+    |> aggregateWindow(every: 1h, fn: count, createEmpty: false)
+    |> set(key: "_measurement", value: %s)
+    |> set(key: "_field", value: "count")
+    |> to(bucket: %s, org: %s)
+`, strconv.Quote(name), strconv.Quote(a.bucket), strconv.Quote(source), strings.Join(columns, ", "),
+		strconv.Quote(target), strconv.Quote(destination), strconv.Quote(a.org))
+}
+
+func (a *influxDB2Admin) installRollup(ctx context.Context, source, target, destination string, tags []string) error {
+	orgID, err := a.orgID(ctx)
+	if err != nil {
+		return err
+	}
+	name := rollupName(target)
+	var resp struct {
+		Tasks []struct {
+			ID string `json:"id"`
+		} `json:"tasks"`
+	}
+	query := url.Values{"orgID": {orgID}, "name": {name}}
+	if err := a.do(ctx, http.MethodGet, "/api/v2/tasks", query, nil, &resp, http.StatusOK); err != nil {
+		return err
+	}
+	if destination == "" {
+		destination = a.bucket
+	}
+	flux := a.rollupFlux(name, source, target, destination, tags)
+	if len(resp.Tasks) > 0 {
+		update := map[string]string{"flux": flux, "status": "active"}
+		return a.do(ctx, http.MethodPatch, "/api/v2/tasks/"+url.PathEscape(resp.Tasks[0].ID), nil, update, nil, http.StatusOK)
+	}
+	created := map[string]string{"orgID": orgID, "flux": flux, "status": "active"}
+	return a.do(ctx, http.MethodPost, "/api/v2/tasks", nil, created, nil, http.StatusCreated)
+}
+
+// influxDB3Admin manages a database of InfluxDB 3 through its API.
+type influxDB3Admin struct {
+	adminClient
+	database string
+}
+
+func newInfluxDB3Admin(config influxdb3.ClientConfig) *influxDB3Admin {
+	base, _ := url.Parse(config.Host)
+	header := http.Header{}
+	if config.Token != "" {
+		scheme := config.AuthScheme
+		if scheme == "" {
+			scheme = "Token"
+		}
+		header.Set("Authorization", scheme+" "+config.Token)
+	}
+	a := &influxDB3Admin{
+		adminClient: adminClient{client: config.HTTPClient, header: header},
+		database:    config.Database,
+	}
+	if base != nil {
+		a.base = *base
+	}
+	return a
+}
+
+type databaseConfig struct {
+	Name            string `json:"db"`
+	RetentionPeriod string `json:"retention_period,omitempty"`
+}
+
+func (a *influxDB3Admin) ensureRetention(ctx context.Context, retention time.Duration) error {
+	if a.database == "" {
+		return errors.New("missing database")
+	}
+	db := databaseConfig{Name: a.database}
+	if retention > 0 {
+		db.RetentionPeriod = fmt.Sprintf("%ds", int64(retention/time.Second))
+	}
+	err := a.do(ctx, http.MethodPost, "/api/v3/configure/database", nil, db, nil, http.StatusOK, http.StatusCreated)
+	var writeErr *WriteError
+	if !errors.As(err, &writeErr) || writeErr.StatusCode != http.StatusConflict {
+		return err
+	}
+	if retention == 0 {
+		// the database exists, and a zero retention only applies to new ones
+		return nil
+	}
+	// the database exists, possibly with another retention
+	return a.do(ctx, http.MethodPut, "/api/v3/configure/database", nil, db, nil, http.StatusOK, http.StatusNoContent)
+}
+
+func (a *influxDB3Admin) installRollup(context.Context, string, string, string, []string) error {
+	return fmt.Errorf("%w: InfluxDB 3 has no continuous aggregation", ErrAdminUnsupported)
+}
@@ -0,0 +1,274 @@
+package influxlogger
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// apiRequests returns the requests received by s, leaving out the
+// connection the influxdb3 client opens for queries.
+func apiRequests(s *testServer) []request {
+	return slices.DeleteFunc(s.received(), func(r request) bool {
+		return r.method == "PRI"
+	})
+}
+
+func TestEnsureRetention(t *testing.T) {
+	const retention = 7 * 24 * time.Hour
+	ctx := context.Background()
+	t.Run("influxdb1", func(t *testing.T) {
+		server := newTestServer(t, func(rw http.ResponseWriter, r request, n int) {
+			if strings.HasPrefix(r.query.Get("q"), "SHOW RETENTION POLICIES") {
+				_, _ = io.WriteString(rw, `{"results":[{"series":[{"columns":["name","duration","default"],`+
+					`"values":[["autogen","0s",true]]}]}]}`)
+				return
+			}
+			_, _ = io.WriteString(rw, `{"results":[{}]}`)
+		})
+		writer := newTestWriter(t, "influxdb1+"+server.URL+"?db=logs", 0)
+		if err := writer.EnsureRetention(ctx, retention); err != nil {
+			t.Fatal(err)
+		}
+		var statements []string
+		for _, r := range server.received() {
+			statements = append(statements, r.query.Get("q"))
+		}
+		want := []string{
+			`CREATE DATABASE "logs"`,
+			`SHOW RETENTION POLICIES ON "logs"`,
+			`ALTER RETENTION POLICY "autogen" ON "logs" DURATION 604800s`,
+		}
+		if !slices.Equal(statements, want) {
+			t.Errorf("got statements %q, want %q", statements, want)
+		}
+	})
+	t.Run("influxdb2", func(t *testing.T) {
+		server := newTestServer(t, func(rw http.ResponseWriter, r request, n int) {
+			switch {
+			case r.path == "/api/v2/orgs":
+				_, _ = io.WriteString(rw, `{"orgs":[{"id":"org1"}]}`)
+			case r.method == http.MethodGet:
+				_, _ = io.WriteString(rw, `{"buckets":[{"id":"bucket1","name":"logs","retentionRules":[]}]}`)
+			default:
+				_, _ = io.WriteString(rw, `{}`)
+			}
+		})
+		writer := newTestWriter(t, "influxdb2+"+server.URL+"?org=acme&bucket=logs&token=secret", 0)
+		if err := writer.EnsureRetention(ctx, retention); err != nil {
+			t.Fatal(err)
+		}
+		requests := server.received()
+		if len(requests) != 3 {
+			t.Fatalf("got %d requests, want 3", len(requests))
+		}
+		if got := requests[1].query.Get("orgID"); got != "org1" {
+			t.Errorf("looked up the bucket in org %q", got)
+		}
+		update := requests[2]
+		if update.method != http.MethodPatch || update.path != "/api/v2/buckets/bucket1" {
+			t.Fatalf("got %s %s, want the bucket to be updated", update.method, update.path)
+		}
+		if want := `{"retentionRules":[{"type":"expire","everySeconds":604800}]}`; update.body != want {
+			t.Errorf("got body %s, want %s", update.body, want)
+		}
+	})
+	t.Run("influxdb3", func(t *testing.T) {
+		server := newTestServer(t, func(rw http.ResponseWriter, r request, n int) {
+			if r.method == http.MethodPost {
+				rw.WriteHeader(http.StatusConflict)
+			}
+		})
+		writer := newTestWriter(t, server.URL+"?token=secret&database=logs", 0)
+		if err := writer.EnsureRetention(ctx, retention); err != nil {
+			t.Fatal(err)
+		}
+		requests := apiRequests(server)
+		if len(requests) != 2 || requests[1].method != http.MethodPut {
+			t.Fatalf("got %d requests, want the existing database to be updated", len(requests))
+		}
+		if want := `{"db":"logs","retention_period":"604800s"}`; requests[1].body != want {
+			t.Errorf("got body %s, want %s", requests[1].body, want)
+		}
+		// a zero retention leaves an existing database alone
+		if err := writer.EnsureRetention(ctx, 0); err != nil {
+			t.Fatal(err)
+		}
+		if n := len(apiRequests(server)); n != 3 {
+			t.Errorf("got %d requests, want 3", n)
+		}
+	})
+}
+
+func TestEnsureRetentionUnsupported(t *testing.T) {
+	tests := []struct {
+		name      string
+		writer    func(t *testing.T) *LogWriter
+		retention time.Duration
+		want      error
+	}{
+		{"line protocol", func(t *testing.T) *LogWriter {
+			return newTestWriter(t, "lp+http://localhost:8086/write", 0)
+		}, time.Hour, ErrAdminUnsupported},
+		{"custom backend", func(t *testing.T) *LogWriter {
+			return newTestWriter(t, "", 0, WithBackend(&recordingBackend{}))
+		}, time.Hour, ErrAdminUnsupported},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.writer(t).EnsureRetention(context.Background(), tt.retention); !errors.Is(err, tt.want) {
+				t.Errorf("got error %v, want %v", err, tt.want)
+			}
+		})
+	}
+	writer := newTestWriter(t, "", 0, WithBackend(&recordingBackend{}))
+	if err := writer.EnsureRetention(context.Background(), -time.Hour); err == nil || errors.Is(err, ErrAdminUnsupported) {
+		t.Errorf("got error %v, want a negative retention to be rejected", err)
+	}
+}
+
+func TestInstallHourlyRollup(t *testing.T) {
+	const target = "log_counts"
+	ctx := context.Background()
+	t.Run("influxdb1", func(t *testing.T) {
+		const query = `SELECT count("message") AS "count" INTO %s FROM %s GROUP BY time(1h), "severity", "appname", "host"`
+		tests := []struct {
+			name        string
+			rp          string
+			destination string
+			existing    bool
+			into, from  string
+		}{
+			{"default", "", "", false, `"log_counts"`, `"syslog"`},
+			{"retention policy", "raw", "", false, `"raw"."log_counts"`, `"raw"."syslog"`},
+			{"destination", "raw", "long", true, `"long"."log_counts"`, `"raw"."syslog"`},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				server := newTestServer(t, func(rw http.ResponseWriter, r request, n int) {
+					if r.query.Get("q") == "SHOW CONTINUOUS QUERIES" && tt.existing {
+						_, _ = io.WriteString(rw, `{"results":[{"series":[{"name":"logs","columns":["name","query"],`+
+							`"values":[["rollup_log_counts","CREATE CONTINUOUS QUERY ..."]]}]}]}`)
+						return
+					}
+					_, _ = io.WriteString(rw, `{"results":[{}]}`)
+				})
+				connection := "influxdb1+" + server.URL + "?db=logs"
+				if tt.rp != "" {
+					connection += "&rp=" + tt.rp
+				}
+				writer := newTestWriter(t, connection, 0)
+				if err := writer.InstallHourlyRollup(ctx, target, tt.destination); err != nil {
+					t.Fatal(err)
+				}
+				var statements []string
+				for _, r := range server.received() {
+					statements = append(statements, r.query.Get("q"))
+				}
+				want := []string{"SHOW CONTINUOUS QUERIES"}
+				if tt.existing {
+					want = append(want, `DROP CONTINUOUS QUERY "rollup_log_counts" ON "logs"`)
+				}
+				want = append(want, `CREATE CONTINUOUS QUERY "rollup_log_counts" ON "logs" BEGIN `+
+					fmt.Sprintf(query, tt.into, tt.from)+` END`)
+				if !slices.Equal(statements, want) {
+					t.Errorf("got statements %q, want %q", statements, want)
+				}
+			})
+		}
+	})
+	t.Run("influxdb2", func(t *testing.T) {
+		const flux = `option task = {name: "rollup_log_counts", every: 1h}
+
+from(bucket: "logs")
+    |> range(start: -task.every)
+    |> filter(fn: (r) => r._measurement == "syslog" and r._field == "message")
+    |> group(columns: ["severity", "appname", "host"])
+    |> aggregateWindow(every: 1h, fn: count, createEmpty: false)
+    |> set(key: "_measurement", value: "log_counts")
+    |> set(key: "_field", value: "count")
+    |> to(bucket: %q, org: "acme")
+`
+		tests := []struct {
+			name        string
+			destination string
+			existing    bool
+			bucket      string
+		}{
+			{"default", "", false, "logs"},
+			{"destination", "archive", false, "archive"},
+			{"existing", "archive", true, "archive"},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				server := newTestServer(t, func(rw http.ResponseWriter, r request, n int) {
+					switch {
+					case r.path == "/api/v2/orgs":
+						_, _ = io.WriteString(rw, `{"orgs":[{"id":"org1"}]}`)
+					case r.method == http.MethodGet && tt.existing:
+						_, _ = io.WriteString(rw, `{"tasks":[{"id":"task1"}]}`)
+					case r.method == http.MethodGet:
+						_, _ = io.WriteString(rw, `{"tasks":[]}`)
+					case r.method == http.MethodPost:
+						rw.WriteHeader(http.StatusCreated)
+						_, _ = io.WriteString(rw, `{}`)
+					default:
+						_, _ = io.WriteString(rw, `{}`)
+					}
+				})
+				writer := newTestWriter(t, "influxdb2+"+server.URL+"?org=acme&bucket=logs&token=secret", 0)
+				if err := writer.InstallHourlyRollup(ctx, target, tt.destination); err != nil {
+					t.Fatal(err)
+				}
+				requests := server.received()
+				if len(requests) != 3 {
+					t.Fatalf("got %d requests, want 3", len(requests))
+				}
+				if got := requests[1].query.Get("name"); got != "rollup_log_counts" {
+					t.Errorf("looked up task %q", got)
+				}
+				installed := requests[2]
+				method, path := http.MethodPost, "/api/v2/tasks"
+				if tt.existing {
+					method, path = http.MethodPatch, "/api/v2/tasks/task1"
+				}
+				if installed.method != method || installed.path != path {
+					t.Fatalf("got %s %s, want %s %s", installed.method, installed.path, method, path)
+				}
+				var task map[string]string
+				if err := json.Unmarshal([]byte(installed.body), &task); err != nil {
+					t.Fatal(err)
+				}
+				if want := fmt.Sprintf(flux, tt.bucket); task["flux"] != want {
+					t.Errorf("got flux\n%s\nwant\n%s", task["flux"], want)
+				}
+				if task["status"] != "active" {
+					t.Errorf("got status %q, want active", task["status"])
+				}
+			})
+		}
+	})
+	t.Run("influxdb3", func(t *testing.T) {
+		server := newTestServer(t, func(rw http.ResponseWriter, r request, n int) {})
+		writer := newTestWriter(t, server.URL+"?token=secret&database=logs", 0)
+		if err := writer.InstallHourlyRollup(ctx, target, ""); !errors.Is(err, ErrAdminUnsupported) {
+			t.Errorf("got error %v, want ErrAdminUnsupported", err)
+		}
+		if requests := apiRequests(server); len(requests) != 0 {
+			t.Errorf("got %d requests, want none", len(requests))
+		}
+	})
+	writer := newTestWriter(t, "influxdb1+http://localhost:8086?db=logs", 0)
+	for _, target := range []string{"", writer.measurement} {
+		if err := writer.InstallHourlyRollup(ctx, target, ""); err == nil {
+			t.Errorf("expected rollup measurement %q to be rejected", target)
+		}
+	}
+}
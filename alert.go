@@ -0,0 +1,38 @@
+package influxlogger
+
+import (
+	"sync"
+	"time"
+)
+
+// errorRateAlert counts error-level entries over a sliding window and calls
+// alert once more than threshold of them fall within it.
+type errorRateAlert struct {
+	threshold int
+	window    time.Duration
+	alert     func(count int, last Entry)
+	mutex     sync.Mutex
+	// times holds the arrival time of every error still within the window
+	times []time.Time
+}
+
+// observe is registered as an error-level hook.
+func (a *errorRateAlert) observe(e Entry) {
+	now := time.Now()
+	a.mutex.Lock()
+	expired := 0
+	for expired < len(a.times) && now.Sub(a.times[expired]) > a.window {
+		expired++
+	}
+	a.times = append(a.times[expired:], now)
+	count := len(a.times)
+	fire := count > a.threshold
+	if fire {
+		// start over, so a sustained burst alerts once per threshold
+		a.times = a.times[:0]
+	}
+	a.mutex.Unlock()
+	if fire {
+		a.alert(count, e)
+	}
+}
@@ -0,0 +1,78 @@
+package influxlogger
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hadi77ir/go-logging"
+)
+
+func TestErrorRateAlert(t *testing.T) {
+	type alert struct {
+		count   int
+		message string
+	}
+	var alerts []alert
+	writer := newTestWriter(t, "", 0,
+		WithBackend(&recordingBackend{}),
+		WithErrorRateAlert(2, time.Hour, func(count int, last Entry) {
+			alerts = append(alerts, alert{count, last.Message})
+		}))
+	entries := []struct {
+		level   logging.Level
+		message string
+	}{
+		{logging.ErrorLevel, "1"},
+		{logging.WarnLevel, "ignored"},
+		{logging.ErrorLevel, "2"},
+		{logging.FatalLevel, "3"},
+		{logging.ErrorLevel, "4"},
+		{logging.ErrorLevel, "5"},
+		{logging.ErrorLevel, "6"},
+	}
+	for _, e := range entries {
+		if err := writer.Write(e.level, []any{e.message}, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// the count starts over after every alert
+	want := []alert{{3, "3"}, {3, "6"}}
+	if len(alerts) != len(want) || alerts[0] != want[0] || alerts[1] != want[1] {
+		t.Errorf("got alerts %v, want %v", alerts, want)
+	}
+}
+
+func TestErrorRateAlertWindow(t *testing.T) {
+	var count int
+	a := &errorRateAlert{threshold: 1, window: 20 * time.Millisecond, alert: func(int, Entry) { count++ }}
+	a.observe(Entry{})
+	time.Sleep(40 * time.Millisecond)
+	// the first error has left the window
+	a.observe(Entry{})
+	if count != 0 {
+		t.Errorf("got %d alerts, want none", count)
+	}
+	a.observe(Entry{})
+	if count != 1 {
+		t.Errorf("got %d alerts, want 1", count)
+	}
+}
+
+func TestWithErrorRateAlertInvalid(t *testing.T) {
+	alert := func(int, Entry) {}
+	tests := []struct {
+		name      string
+		threshold int
+		window    time.Duration
+		alert     func(int, Entry)
+	}{
+		{"threshold", 0, time.Second, alert},
+		{"window", 1, 0, alert},
+		{"callback", 1, time.Second, nil},
+	}
+	for _, tt := range tests {
+		if err := optionsError(WithErrorRateAlert(tt.threshold, tt.window, tt.alert)); err == nil {
+			t.Errorf("expected an invalid %s to be rejected", tt.name)
+		}
+	}
+}
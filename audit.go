@@ -0,0 +1,100 @@
+package influxlogger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"time"
+
+	"github.com/hadi77ir/go-logging"
+)
+
+// DefaultAuditMeasurement is the measurement audit events are written to.
+const DefaultAuditMeasurement = "audit"
+
+// AuditEvent records who did what to which target, and how it turned out.
+// Actor, Action, Target and Outcome are required.
+type AuditEvent struct {
+	Actor   string
+	Action  string
+	Target  string
+	Outcome string
+	// Fields are written alongside the required fields, which take
+	// precedence over fields of the same name
+	Fields logging.Fields
+}
+
+func (e AuditEvent) validate() error {
+	var errs []error
+	for name, value := range map[string]string{
+		"actor":   e.Actor,
+		"action":  e.Action,
+		"target":  e.Target,
+		"outcome": e.Outcome,
+	} {
+		if value == "" {
+			errs = append(errs, fmt.Errorf("missing audit %s", name))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// AuditLogger writes audit events to their own measurement. Events are
+// never buffered, filtered by level or dropped: every call to Log delivers
+// its event before returning, and reports whether that succeeded.
+type AuditLogger struct {
+	writer *LogWriter
+}
+
+// NewAuditLogger creates an AuditLogger writing to DefaultAuditMeasurement,
+// unless WithMeasurement is passed in opts.
+func NewAuditLogger(connection, appName, host, procId string, opts ...Option) (*AuditLogger, error) {
+	opts = append([]Option{WithMeasurement(DefaultAuditMeasurement)}, opts...)
+	writer, err := NewLogWriter(connection, appName, host, procId, 0, 0, opts...)
+	if err != nil {
+		return nil, err
+	}
+	// an event the server rejected wasn't written, dead-letter sink or not
+	writer.strictRejects = true
+	return &AuditLogger{writer: writer}, nil
+}
+
+// Log writes event at info level and returns once it is written, or with
+// the error that kept it from being written.
+func (l *AuditLogger) Log(event AuditEvent) (err error) {
+	if err := event.validate(); err != nil {
+		return err
+	}
+	w := l.writer
+	defer w.recoverPanic(&err)
+	if w.ctx.Err() != nil {
+		return ErrClosed
+	}
+	fields := make(logging.Fields, len(event.Fields)+4)
+	maps.Copy(fields, event.Fields)
+	fields["actor"] = event.Actor
+	fields["action"] = event.Action
+	fields["target"] = event.Target
+	fields["outcome"] = event.Outcome
+	e := &Entry{
+		Level:   logging.InfoLevel,
+		Time:    time.Now(),
+		Message: fmt.Sprintf("%s %s %s: %s", event.Actor, event.Action, event.Target, event.Outcome),
+		Fields:  fields,
+	}
+	w.printEntry(e)
+	// unlike other entries, audit events are written whatever the level
+	// of the writer
+	return w.submitEntry(e)
+}
+
+// Close closes the underlying writer.
+func (l *AuditLogger) Close() error {
+	return l.writer.Close()
+}
+
+// Shutdown is like Close, but gives up once ctx is done.
+func (l *AuditLogger) Shutdown(ctx context.Context) error {
+	return l.writer.Shutdown(ctx)
+}
@@ -0,0 +1,105 @@
+package influxlogger
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/hadi77ir/go-logging"
+)
+
+func newTestAuditLogger(t *testing.T, connection string, opts ...Option) *AuditLogger {
+	t.Helper()
+	logger, err := NewAuditLogger(connection, "test", "localhost", "1", opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = logger.Close() })
+	return logger
+}
+
+func TestAuditLogger(t *testing.T) {
+	backend := &recordingBackend{}
+	// events are written whatever the level of the writer
+	logger := newTestAuditLogger(t, "", WithBackend(backend), WithLevel(logging.ErrorLevel))
+	err := logger.Log(AuditEvent{
+		Actor:   "alice",
+		Action:  "delete",
+		Target:  "bucket/logs",
+		Outcome: "success",
+		Fields:  logging.Fields{"actor": "mallory", "ip": "10.0.0.1"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := backend.written(t)
+	if len(lines) != 1 {
+		t.Fatalf("got %d lines, want 1", len(lines))
+	}
+	l := lines[0]
+	if l.measurement != DefaultAuditMeasurement {
+		t.Errorf("got measurement %q, want %q", l.measurement, DefaultAuditMeasurement)
+	}
+	want := map[string]any{
+		"fields.actor":   "alice",
+		"fields.action":  "delete",
+		"fields.target":  "bucket/logs",
+		"fields.outcome": "success",
+		"fields.ip":      "10.0.0.1",
+		"message":        "alice delete bucket/logs: success",
+	}
+	for key, value := range want {
+		if l.fields[key] != value {
+			t.Errorf("got %s %#v, want %#v", key, l.fields[key], value)
+		}
+	}
+	backend = &recordingBackend{}
+	logger = newTestAuditLogger(t, "", WithBackend(backend), WithMeasurement("events"))
+	if err := logger.Log(AuditEvent{Actor: "a", Action: "b", Target: "c", Outcome: "d"}); err != nil {
+		t.Fatal(err)
+	}
+	if l := backend.written(t)[0]; l.measurement != "events" {
+		t.Errorf("got measurement %q, want events", l.measurement)
+	}
+}
+
+func TestAuditLoggerErrors(t *testing.T) {
+	backend := &recordingBackend{}
+	logger := newTestAuditLogger(t, "", WithBackend(backend))
+	err := logger.Log(AuditEvent{Actor: "alice", Outcome: "success"})
+	for _, missing := range []string{"action", "target"} {
+		if err == nil || !strings.Contains(err.Error(), "missing audit "+missing) {
+			t.Errorf("got error %v, want missing %s reported", err, missing)
+		}
+	}
+	if lines := backend.written(t); len(lines) != 0 {
+		t.Errorf("got %d lines written for an invalid event", len(lines))
+	}
+
+	server := newTestServer(t, func(rw http.ResponseWriter, r request, n int) {
+		rw.WriteHeader(http.StatusBadRequest)
+		_, _ = io.WriteString(rw, `{"error":"partial write of line protocol occurred","data":[`+
+			`{"original_line":"","line_number":1,"error_message":"invalid column type"}]}`)
+	})
+	// a rejected event is reported even with a dead-letter sink
+	var rejected []Entry
+	logger = newTestAuditLogger(t, "lp+"+server.URL+"/write", WithDeadLetter(func(e Entry, reason error) {
+		rejected = append(rejected, e)
+	}))
+	err = logger.Log(AuditEvent{Actor: "alice", Action: "delete", Target: "bucket/logs", Outcome: "success"})
+	var writeErr *WriteError
+	if !errors.As(err, &writeErr) {
+		t.Errorf("got error %v, want the rejection", err)
+	}
+	if len(rejected) != 1 {
+		t.Errorf("got %d dead-lettered events, want 1", len(rejected))
+	}
+	if err := logger.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := logger.Log(AuditEvent{Actor: "a", Action: "b", Target: "c", Outcome: "d"}); !errors.Is(err, ErrClosed) {
+		t.Errorf("got error %v after closing, want ErrClosed", err)
+	}
+}
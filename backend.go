@@ -0,0 +1,67 @@
+package influxlogger
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/InfluxCommunity/influxdb3-go/v2/influxdb3"
+	"github.com/influxdata/line-protocol/v2/lineprotocol"
+)
+
+// Backend delivers batches of line protocol to a destination.
+type Backend interface {
+	// WriteLineProtocol writes one or more complete lines of line protocol
+	// with nanosecond timestamps. Rejected requests should be reported as
+	// *WriteError so that rate limits and partial writes are handled.
+	WriteLineProtocol(ctx context.Context, lines []byte) error
+	// Close releases the resources held by the backend.
+	Close() error
+}
+
+// newBackend picks a backend by the scheme of the connection string.
+// Connection strings without a recognized prefix are handed to the
+// influxdb3 client.
+func newBackend(connection string, options *transportOptions) (Backend, error) {
+	scheme, _, _ := strings.Cut(connection, "://")
+	switch scheme {
+	case "influxdb1+http", "influxdb1+https":
+		return newInfluxDB1Backend(connection, options)
+	case "influxdb2+http", "influxdb2+https":
+		return newInfluxDB2Backend(connection, options)
+	case "lp+http", "lp+https":
+		return newLineProtocolBackend(connection, options)
+	case "telegraf+tcp", "telegraf+unix":
+		return newSocketBackend(connection, options)
+	case "udp":
+		return newUDPBackend(connection)
+	}
+	client, config, err := newClient(connection, options)
+	if err != nil {
+		return nil, err
+	}
+	return &clientBackend{
+		client:    client,
+		admin:     newInfluxDB3Admin(config),
+		precision: config.WriteOptions.Precision.Duration(),
+	}, nil
+}
+
+// clientBackend writes through the influxdb3 client.
+type clientBackend struct {
+	client *influxdb3.Client
+	admin  *influxDB3Admin
+	// precision is the one points are written with; line protocol is
+	// always written in nanoseconds
+	precision time.Duration
+}
+
+func (b *clientBackend) WriteLineProtocol(ctx context.Context, lines []byte) error {
+	return clientWrite(ctx, func(ctx context.Context) error {
+		return b.client.Write(ctx, lines, influxdb3.WithPrecision(lineprotocol.Nanosecond))
+	})
+}
+
+func (b *clientBackend) Close() error {
+	return b.client.Close()
+}
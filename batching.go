@@ -0,0 +1,75 @@
+package influxlogger
+
+import (
+	"context"
+	"errors"
+
+	"github.com/InfluxCommunity/influxdb3-go/v2/influxdb3"
+)
+
+var errBatcherFull = errors.New("client batcher is full, dropping entry")
+
+// writeBatched adds entries, the chunks of a message, to the client's
+// batcher as points, writing the batch once it is full. While writes are
+// paused, the batcher holds up to the buffer limit of points, and messages
+// beyond it are dropped whole.
+func (w *LogWriter) writeBatched(ctx context.Context, entries []*Entry) error {
+	points := make([]*influxdb3.Point, len(entries))
+	for i, e := range entries {
+		points[i] = w.newPoint(e)
+	}
+	w.flushMutex.Lock()
+	defer w.flushMutex.Unlock()
+	defer w.storeBufferLen()
+	var flushErr error
+	if w.batched+len(points) > w.bufferCap && !w.paused() {
+		// a pause ended with a full batcher
+		flushErr = w.flushBatcher(ctx)
+	}
+	if w.batched+len(points) > w.bufferCap {
+		w.drops.bufferFull.Add(uint64(len(points)))
+		return errors.Join(flushErr, errBatcherFull)
+	}
+	w.batcher.Add(points...)
+	w.batched += len(points)
+	if w.batcher.Ready() && !w.paused() {
+		return errors.Join(flushErr, w.flushBatcher(ctx))
+	}
+	return errors.Join(flushErr, w.notifyFlush(ctx, entries[0]))
+}
+
+// flushBatcher writes the points held by the batcher, a batch per request,
+// until none are left. Points are no longer tied to entries, so a
+// rate-limited batch is put back as a whole, keeping it and the batches
+// after it for once the pause is over, and rejected points can't reach the
+// dead-letter sink.
+func (w *LogWriter) flushBatcher(ctx context.Context) error {
+	var errs []error
+	for w.batched > 0 {
+		points := w.batcher.Emit()
+		if len(points) == 0 {
+			w.batched = 0
+			break
+		}
+		w.batched -= len(points)
+		err := w.writeBatch(ctx, points)
+		if retryAfter, ok := rateLimited(err); ok {
+			w.pause(retryAfter)
+			w.batcher.Add(points...)
+			w.batched += len(points)
+			break
+		}
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// writeBatch writes a batch emitted by the batcher.
+func (w *LogWriter) writeBatch(ctx context.Context, points []*influxdb3.Point) error {
+	if w.writeTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, w.writeTimeout)
+		defer cancel()
+	}
+	return w.writePoints(ctx, points)
+}
@@ -0,0 +1,83 @@
+package influxlogger
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/hadi77ir/go-logging"
+)
+
+// writes returns the write requests received by s, leaving out the
+// connection the client opens for queries.
+func writes(s *testServer) []request {
+	return slices.DeleteFunc(s.received(), func(r request) bool {
+		return r.method != http.MethodPost
+	})
+}
+
+func TestClientBatching(t *testing.T) {
+	var server *testServer
+	server = newTestServer(t, func(rw http.ResponseWriter, r request, n int) {
+		if len(writes(server)) == 2 {
+			rw.Header().Set("Retry-After", "3600")
+			rw.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		rw.WriteHeader(http.StatusNoContent)
+	})
+	writer := newTestWriter(t, server.URL+"?token=secret&database=logs", 3, WithClientBatching())
+	writeMessages(t, writer, "a", "b")
+	if n := len(writes(server)); n != 0 {
+		t.Fatalf("got %d requests before the batch was full", n)
+	}
+	// filling the batch writes it
+	writeMessages(t, writer, "c")
+	requests := writes(server)
+	if len(requests) != 1 {
+		t.Fatalf("got %d requests, want 1", len(requests))
+	}
+	if got := messages(t, requests[0].body); !slices.Equal(got, []string{"a", "b", "c"}) {
+		t.Errorf("got messages %q, want a, b and c", got)
+	}
+
+	// a rate-limited batch is kept, and entries beyond the limit dropped
+	writeMessages(t, writer, "d", "e", "f")
+	if !writer.paused() {
+		t.Fatal("expected the rate-limited batch to pause writes")
+	}
+	if err := writer.Write(logging.InfoLevel, []any{"g"}, nil); !errors.Is(err, errBatcherFull) {
+		t.Errorf("got error %v, want errBatcherFull", err)
+	}
+	if got := writer.BufferLen(); got != 3 {
+		t.Errorf("got buffer length %d, want 3", got)
+	}
+	if got := writer.Stats().BufferFull; got != 1 {
+		t.Errorf("got %d entries dropped, want 1", got)
+	}
+
+	// once the pause is over, the kept batch is written
+	writer.pausedUntil.Store(0)
+	if err := writer.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	requests = writes(server)
+	if len(requests) != 3 {
+		t.Fatalf("got %d requests, want 3", len(requests))
+	}
+	if got := messages(t, requests[2].body); !slices.Equal(got, []string{"d", "e", "f"}) {
+		t.Errorf("got messages %q, want d, e and f", got)
+	}
+	if got := writer.BufferLen(); got != 0 {
+		t.Errorf("got buffer length %d after the flush, want 0", got)
+	}
+}
+
+func TestClientBatchingRequiresClient(t *testing.T) {
+	if _, err := NewLogWriter("lp+http://localhost:8086/write", "test", "localhost", "1", time.Hour, 3, WithClientBatching()); err == nil {
+		t.Error("expected client batching without the influxdb3 client to be rejected")
+	}
+}
@@ -0,0 +1,89 @@
+package influxlogger
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"time"
+	"unicode/utf8"
+)
+
+// OversizePolicy decides how messages longer than the message limit are
+// written.
+type OversizePolicy int
+
+const (
+	// OversizeTruncate cuts messages down to the limit.
+	OversizeTruncate OversizePolicy = iota
+	// OversizeChunk splits messages into entries of at most the limit,
+	// linked by the chunk_id, chunk_index and chunk_count fields, so the
+	// full message can be put back together from InfluxDB.
+	OversizeChunk
+)
+
+// chunk links the entries a message was split into.
+type chunk struct {
+	id    string
+	index int
+	count int
+}
+
+// splitMessage returns e with its message cut to the limit, or the entries
+// its message is split into. Chunks are spaced by the write precision, since
+// points of the same series and time overwrite each other.
+func (w *LogWriter) splitMessage(e *Entry) []*Entry {
+	if w.maxMessage <= 0 || len(e.Message) <= w.maxMessage {
+		return []*Entry{e}
+	}
+	if w.oversize == OversizeTruncate {
+		e.Message = e.Message[:cutMessage(e.Message, w.maxMessage)]
+		return []*Entry{e}
+	}
+	var parts []string
+	for message := e.Message; message != ""; {
+		n := cutMessage(message, w.maxMessage)
+		parts = append(parts, message[:n])
+		message = message[n:]
+	}
+	id := fmt.Sprintf("%016x", rand.Uint64())
+	entries := make([]*Entry, len(parts))
+	for i, part := range parts {
+		c := *e
+		c.Message = part
+		c.Time = e.Time.Add(time.Duration(i) * w.precision)
+		c.chunk = &chunk{id: id, index: i, count: len(parts)}
+		entries[i] = &c
+	}
+	return entries
+}
+
+// cutMessage returns the length of the longest prefix of message of at
+// most limit bytes that doesn't split a character. A single character
+// longer than limit is kept whole.
+func cutMessage(message string, limit int) int {
+	if len(message) <= limit {
+		return len(message)
+	}
+	n := limit
+	for n > 0 && !utf8.RuneStart(message[n]) {
+		n--
+	}
+	if n == 0 {
+		_, n = utf8.DecodeRuneInString(message)
+	}
+	return n
+}
+
+// eachChunkField calls fn for the fields linking e to the other chunks of
+// its message, if it is one.
+func eachChunkField(e *Entry, fn func(key string, value any) error) error {
+	if e.chunk == nil {
+		return nil
+	}
+	if err := fn("chunk_id", e.chunk.id); err != nil {
+		return err
+	}
+	if err := fn("chunk_index", e.chunk.index); err != nil {
+		return err
+	}
+	return fn("chunk_count", e.chunk.count)
+}
@@ -0,0 +1,124 @@
+package influxlogger
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hadi77ir/go-logging"
+	"github.com/hadi77ir/go-ringqueue"
+)
+
+func TestOversizeChunk(t *testing.T) {
+	const message = "hello, world!"
+	server := newTestServer(t, func(rw http.ResponseWriter, r request, n int) {
+		rw.WriteHeader(http.StatusNoContent)
+	})
+	writer := newTestWriter(t, "lp+"+server.URL+"/write", 0, WithMaxMessageLength(5, OversizeChunk))
+	writeMessages(t, writer, message)
+	requests := server.received()
+	if len(requests) != 1 {
+		t.Fatalf("got %d requests, want 1", len(requests))
+	}
+	lines := decodeLines(t, requests[0].body)
+	if len(lines) != 3 {
+		t.Fatalf("got %d chunks, want 3", len(lines))
+	}
+	var joined strings.Builder
+	for i, l := range lines {
+		joined.WriteString(l.fields["message"].(string))
+		if l.fields["chunk_id"] != lines[0].fields["chunk_id"] {
+			t.Errorf("chunk %d has id %v, want %v", i, l.fields["chunk_id"], lines[0].fields["chunk_id"])
+		}
+		if l.fields["chunk_index"] != int64(i) || l.fields["chunk_count"] != int64(3) {
+			t.Errorf("chunk %d is numbered %v of %v", i, l.fields["chunk_index"], l.fields["chunk_count"])
+		}
+		if i > 0 && !l.time.After(lines[i-1].time) {
+			t.Errorf("chunk %d isn't later than the one before it", i)
+		}
+	}
+	if joined.String() != message {
+		t.Errorf("chunks join into %q, want %q", joined.String(), message)
+	}
+}
+
+func TestSplitMessage(t *testing.T) {
+	tests := []struct {
+		name    string
+		limit   int
+		policy  OversizePolicy
+		message string
+		want    []string
+	}{
+		{"under the limit", 5, OversizeChunk, "hello", []string{"hello"}},
+		{"truncate", 5, OversizeTruncate, "hello, world!", []string{"hello"}},
+		{"chunk", 5, OversizeChunk, "hello, world!", []string{"hello", ", wor", "ld!"}},
+		{"between characters", 3, OversizeChunk, "héllo", []string{"hé", "llo"}},
+		{"character over the limit", 1, OversizeTruncate, "éa", []string{"é"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writer := newTestWriter(t, "", 0, WithBackend(&recordingBackend{}), WithMaxMessageLength(tt.limit, tt.policy))
+			var got []string
+			for _, e := range writer.splitMessage(&Entry{Message: tt.message}) {
+				got = append(got, e.Message)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChunkBuffering(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    []Option
+		v3      bool
+		fullErr error
+	}{
+		{"buffer", nil, false, ringqueue.ErrFullQueue},
+		{"client batching", []Option{WithClientBatching()}, true, errBatcherFull},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTestServer(t, func(rw http.ResponseWriter, r request, n int) {
+				rw.WriteHeader(http.StatusNoContent)
+			})
+			connection := "lp+" + server.URL + "/write"
+			if tt.v3 {
+				connection = server.URL + "?token=secret&database=logs"
+			}
+			writer := newTestWriter(t, connection, 4, append(tt.opts, WithMaxMessageLength(1, OversizeChunk))...)
+			writeMessages(t, writer, "a", "b")
+			// while paused, a message only partly fitting is dropped whole
+			writer.pause(time.Hour)
+			if err := writer.Write(logging.InfoLevel, []any{"xyz"}, nil); !errors.Is(err, tt.fullErr) {
+				t.Errorf("got error %v, want %v", err, tt.fullErr)
+			}
+			if got := writer.BufferLen(); got != 2 {
+				t.Errorf("got buffer length %d, want 2", got)
+			}
+			if got := writer.Stats().BufferFull; got != 3 {
+				t.Errorf("got %d entries dropped, want 3", got)
+			}
+			// once writes resume, the buffer is flushed to make room
+			writer.pausedUntil.Store(0)
+			writeMessages(t, writer, "xyz")
+			if err := writer.Flush(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, r := range writes(server) {
+				got = append(got, messages(t, r.body)...)
+			}
+			if want := []string{"a", "b", "x", "y", "z"}; !slices.Equal(got, want) {
+				t.Errorf("got messages %q, want %q", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,176 @@
+package influxlogger
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/InfluxCommunity/influxdb3-go/v2/influxdb3"
+	"github.com/influxdata/line-protocol/v2/lineprotocol"
+)
+
+// defaultHTTPTimeout matches the request timeout of the influxdb3 client.
+const defaultHTTPTimeout = 10 * time.Second
+
+// newClient creates an influxdb3 client from a connection string in the
+// format accepted by influxdb3.NewFromConnectionString, sending its
+// requests through a transport that records rejected responses. The
+// configuration it was created with is returned along with it.
+//
+// A connection string like unix:///var/run/influxdb.sock?token=secret
+// reaches the server through a unix domain socket.
+func newClient(connection string, options *transportOptions) (*influxdb3.Client, influxdb3.ClientConfig, error) {
+	config, err := parseConnectionString(connection)
+	if err != nil {
+		return nil, config, err
+	}
+	transport := options.newTransport()
+	if u, err := url.Parse(config.Host); err == nil && u.Scheme == "unix" {
+		if u.Path == "" {
+			return nil, config, errors.New("missing socket path")
+		}
+		dialUnix(transport, options.dialer(), u.Path)
+		// the host only serves to build request URLs
+		config.Host = "http://localhost"
+	}
+	config.HTTPClient = &http.Client{
+		Timeout:   defaultHTTPTimeout,
+		Transport: &responseTransport{base: options.roundTripper(transport)},
+	}
+	client, err := influxdb3.New(config)
+	return client, config, err
+}
+
+// dialUnix makes transport connect to the unix domain socket at path,
+// whatever the address of the request.
+func dialUnix(transport *http.Transport, dialer *net.Dialer, path string) {
+	transport.Proxy = nil
+	transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+		return dialer.DialContext(ctx, "unix", path)
+	}
+}
+
+func parseConnectionString(connection string) (influxdb3.ClientConfig, error) {
+	var config influxdb3.ClientConfig
+	u, err := url.Parse(connection)
+	if err != nil {
+		return config, err
+	}
+	values := u.Query()
+	u.RawQuery = ""
+	config.Host = u.String()
+	config.Token = values.Get("token")
+	config.AuthScheme = values.Get("authScheme")
+	config.Organization = values.Get("org")
+	config.Database = values.Get("database")
+	writeOptions := influxdb3.DefaultWriteOptions
+	if precision := values.Get("precision"); precision != "" {
+		writeOptions.Precision, err = parsePrecision(precision)
+		if err != nil {
+			return config, err
+		}
+	}
+	if threshold := values.Get("gzipThreshold"); threshold != "" {
+		writeOptions.GzipThreshold, err = strconv.Atoi(threshold)
+		if err != nil {
+			return config, errors.New("invalid gzip threshold")
+		}
+	}
+	config.WriteOptions = &writeOptions
+	return config, nil
+}
+
+func parsePrecision(precision string) (lineprotocol.Precision, error) {
+	switch precision {
+	case "ns", "nanosecond":
+		return lineprotocol.Nanosecond, nil
+	case "us", "microsecond":
+		return lineprotocol.Microsecond, nil
+	case "ms", "millisecond":
+		return lineprotocol.Millisecond, nil
+	case "s", "second":
+		return lineprotocol.Second, nil
+	}
+	return 0, errors.New("invalid precision " + strconv.Quote(precision))
+}
+
+type responseInfoKey struct{}
+
+// maxErrorBody limits how much of a rejected response's body is kept.
+const maxErrorBody = 1 << 20
+
+// responseInfo holds the parts of a rejected response the influxdb3 client
+// doesn't expose in its errors.
+type responseInfo struct {
+	statusCode     int
+	retryAfter     time.Duration
+	retryAfterSent bool
+	body           []byte
+}
+
+// responseTransport fills in the responseInfo found in a request's context
+// when the server rejects the request.
+type responseTransport struct {
+	base http.RoundTripper
+}
+
+func (t *responseTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil || resp.StatusCode < http.StatusBadRequest {
+		return resp, err
+	}
+	if info, ok := req.Context().Value(responseInfoKey{}).(*responseInfo); ok {
+		info.statusCode = resp.StatusCode
+		info.retryAfter, info.retryAfterSent = parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+		// keep a copy of the body and hand the client an equivalent one
+		info.body, err = io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		_ = resp.Body.Close()
+		resp.Body = io.NopCloser(bytes.NewReader(info.body))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return resp, nil
+}
+
+// parseRetryAfter reads a Retry-After header given either in seconds or as
+// an HTTP date, and reports whether it held a valid delay. A date in the
+// past is a delay of zero.
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(date.Sub(now), 0), true
+	}
+	return 0, false
+}
+
+// clientWrite runs write, a request of the influxdb3 client, and turns the
+// error of a rejected request into a *WriteError.
+func clientWrite(ctx context.Context, write func(ctx context.Context) error) error {
+	info := &responseInfo{}
+	err := write(context.WithValue(ctx, responseInfoKey{}, info))
+	if err != nil && info.statusCode != 0 {
+		return &WriteError{
+			StatusCode:     info.statusCode,
+			RetryAfter:     info.retryAfter,
+			RetryAfterSent: info.retryAfterSent,
+			Body:           info.body,
+			Err:            err,
+		}
+	}
+	return err
+}
@@ -0,0 +1,44 @@
+package influxlogger
+
+import (
+	"net"
+	"net/http"
+	"path/filepath"
+	"testing"
+)
+
+func TestUnixConnectionString(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "influxdb.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &http.Server{Handler: http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.Header().Set("X-Path", r.URL.Path)
+		rw.WriteHeader(http.StatusNoContent)
+	})}
+	go func() { _ = server.Serve(listener) }()
+	defer server.Close()
+	// a proxy from the environment must not be used for a local socket
+	t.Setenv("HTTP_PROXY", "http://proxy.invalid:3128")
+
+	_, config, err := newClient("unix://"+socket+"?token=secret&database=logs", &transportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Host != "http://localhost" || config.Token != "secret" || config.Database != "logs" {
+		t.Errorf("got host %q, token %q and database %q", config.Host, config.Token, config.Database)
+	}
+	resp, err := config.HTTPClient.Get(config.Host + "/api/v3/write_lp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if got := resp.Header.Get("X-Path"); resp.StatusCode != http.StatusNoContent || got != "/api/v3/write_lp" {
+		t.Errorf("got status %d for path %q", resp.StatusCode, got)
+	}
+
+	if _, _, err := newClient("unix://?token=secret", &transportOptions{}); err == nil {
+		t.Error("expected a connection string without a socket path to be rejected")
+	}
+}
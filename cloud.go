@@ -0,0 +1,186 @@
+package influxlogger
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/hadi77ir/go-logging"
+)
+
+// cloudMetadataTimeout bounds the time spent asking the instance metadata
+// service where the process runs.
+const cloudMetadataTimeout = time.Second
+
+// maxMetadataBody bounds the size of metadata responses read.
+const maxMetadataBody = 64 << 10
+
+// cloudInstance is where a process runs, as reported by the metadata
+// service of its cloud provider.
+type cloudInstance struct {
+	provider string
+	region   string
+	instance string
+}
+
+// cloudEnricher adds the cloud provider, region and instance from the
+// environment, asking the metadata service at base for those missing.
+type cloudEnricher struct {
+	base   string
+	client *http.Client
+}
+
+// CloudEnricher adds the cloud provider, region and instance the process
+// runs on as the cloud_provider, cloud_region and cloud_instance fields.
+// They are taken from the CLOUD_PROVIDER, CLOUD_REGION and CLOUD_INSTANCE
+// environment variables, and those unset from the instance metadata service
+// of AWS, Google Cloud or Azure, whichever answers first. When
+// CLOUD_PROVIDER is set, only its metadata service is asked. Asking takes
+// up to a second off the cloud, and fields nothing reports are left out.
+// Its fields don't change, so it is meant for WithCachedEnricher.
+func CloudEnricher() Enricher {
+	return &cloudEnricher{
+		base:   "http://169.254.169.254",
+		client: &http.Client{Timeout: cloudMetadataTimeout},
+	}
+}
+
+func (c *cloudEnricher) Enrich(*Entry) logging.Fields {
+	fields := logging.Fields{}
+	for field, env := range map[string]string{
+		"cloud_provider": "CLOUD_PROVIDER",
+		"cloud_region":   "CLOUD_REGION",
+		"cloud_instance": "CLOUD_INSTANCE",
+	} {
+		if value := os.Getenv(env); value != "" {
+			fields[field] = value
+		}
+	}
+	if len(fields) == 3 {
+		return fields
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), cloudMetadataTimeout)
+	defer cancel()
+	for _, probe := range []struct {
+		provider string
+		lookup   func(ctx context.Context) (cloudInstance, error)
+	}{
+		{"aws", c.aws},
+		{"gcp", c.gcp},
+		{"azure", c.azure},
+	} {
+		if provider, ok := fields["cloud_provider"]; ok && provider != probe.provider {
+			continue
+		}
+		instance, err := probe.lookup(ctx)
+		if err != nil {
+			continue
+		}
+		for field, value := range map[string]string{
+			"cloud_provider": instance.provider,
+			"cloud_region":   instance.region,
+			"cloud_instance": instance.instance,
+		} {
+			if _, ok := fields[field]; !ok && value != "" {
+				fields[field] = value
+			}
+		}
+		break
+	}
+	return fields
+}
+
+// aws asks the EC2 instance metadata service, which requires a session
+// token since IMDSv2.
+func (c *cloudEnricher) aws(ctx context.Context) (cloudInstance, error) {
+	token, err := c.get(ctx, http.MethodPut, "/latest/api/token", http.Header{
+		"X-Aws-Ec2-Metadata-Token-Ttl-Seconds": {"60"},
+	})
+	if err != nil {
+		return cloudInstance{}, err
+	}
+	header := http.Header{"X-Aws-Ec2-Metadata-Token": {token}}
+	region, err := c.get(ctx, http.MethodGet, "/latest/meta-data/placement/region", header)
+	if err != nil {
+		return cloudInstance{}, err
+	}
+	instance, err := c.get(ctx, http.MethodGet, "/latest/meta-data/instance-id", header)
+	if err != nil {
+		return cloudInstance{}, err
+	}
+	return cloudInstance{provider: "aws", region: region, instance: instance}, nil
+}
+
+// gcp asks the Compute Engine metadata server, which reports the zone,
+// such as projects/1/zones/europe-west1-b, rather than the region.
+func (c *cloudEnricher) gcp(ctx context.Context) (cloudInstance, error) {
+	header := http.Header{"Metadata-Flavor": {"Google"}}
+	zone, err := c.get(ctx, http.MethodGet, "/computeMetadata/v1/instance/zone", header)
+	if err != nil {
+		return cloudInstance{}, err
+	}
+	instance, err := c.get(ctx, http.MethodGet, "/computeMetadata/v1/instance/id", header)
+	if err != nil {
+		return cloudInstance{}, err
+	}
+	zone = zone[strings.LastIndex(zone, "/")+1:]
+	region := zone
+	if i := strings.LastIndex(zone, "-"); i > 0 {
+		region = zone[:i]
+	}
+	return cloudInstance{provider: "gcp", region: region, instance: instance}, nil
+}
+
+// azure asks the Azure instance metadata service.
+func (c *cloudEnricher) azure(ctx context.Context) (cloudInstance, error) {
+	body, err := c.get(ctx, http.MethodGet, "/metadata/instance/compute?api-version=2021-02-01&format=json", http.Header{
+		"Metadata": {"true"},
+	})
+	if err != nil {
+		return cloudInstance{}, err
+	}
+	var compute struct {
+		Location string `json:"location"`
+		VMID     string `json:"vmId"`
+	}
+	if err := json.Unmarshal([]byte(body), &compute); err != nil {
+		return cloudInstance{}, err
+	}
+	return cloudInstance{provider: "azure", region: compute.Location, instance: compute.VMID}, nil
+}
+
+// get requests path from the metadata service and returns the body of a
+// successful response.
+func (c *cloudEnricher) get(ctx context.Context, method, path string, header http.Header) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.base+path, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header = header
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("metadata service answered %s with status %d", path, resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxMetadataBody))
+	if err != nil {
+		return "", err
+	}
+	value := strings.TrimSpace(string(body))
+	if value == "" {
+		return "", errors.New("metadata service answered " + path + " with an empty body")
+	}
+	return value, nil
+}
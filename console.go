@@ -0,0 +1,117 @@
+package influxlogger
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/hadi77ir/go-logging"
+)
+
+// consoleLevels holds the label and ANSI color of each level on the
+// console.
+var consoleLevels = map[logging.Level]struct {
+	label string
+	color string
+}{
+	logging.TraceLevel: {"TRACE", "\x1b[90m"},
+	logging.DebugLevel: {"DEBUG", "\x1b[36m"},
+	logging.InfoLevel:  {"INFO ", "\x1b[32m"},
+	logging.WarnLevel:  {"WARN ", "\x1b[33m"},
+	logging.ErrorLevel: {"ERROR", "\x1b[31m"},
+	logging.FatalLevel: {"FATAL", "\x1b[1;35m"},
+	logging.PanicLevel: {"PANIC", "\x1b[1;35m"},
+}
+
+const (
+	consoleReset = "\x1b[0m"
+	consoleDim   = "\x1b[2m"
+)
+
+// ConsoleEnv is the environment variable read by
+// WithConsoleFallbackFromEnv. It holds off, on, or the least severe level
+// to print, such as debug.
+const ConsoleEnv = "INFLUXLOGGER_CONSOLE"
+
+// console prints entries in a human-readable form, for local development.
+type console struct {
+	out   io.Writer
+	color bool
+	// level is the least severe level printed, which is the level of the
+	// writer unless levelSet
+	level    logging.Level
+	levelSet bool
+	mutex    sync.Mutex
+	buf      []byte
+}
+
+// write prints e as a single line like
+//
+//	15:04:05.000 INFO  request served path=/index status=200
+func (c *console) write(e *Entry) error {
+	level := consoleLevels[e.Level]
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	buf := c.buf[:0]
+	buf = c.appendStyled(buf, consoleDim, e.Time.Format("15:04:05.000"))
+	buf = append(buf, ' ')
+	buf = c.appendStyled(buf, level.color, level.label)
+	buf = append(buf, ' ')
+	buf = append(buf, e.Message...)
+	keys := make([]string, 0, len(e.Fields))
+	for key := range e.Fields {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		buf = append(buf, ' ')
+		buf = c.appendStyled(buf, consoleDim, key+"=")
+		buf = append(buf, consoleValue(e.Fields[key])...)
+	}
+	buf = append(buf, '\n')
+	c.buf = buf
+	_, err := c.out.Write(buf)
+	return err
+}
+
+func (c *console) appendStyled(buf []byte, style, s string) []byte {
+	if !c.color {
+		return append(buf, s...)
+	}
+	buf = append(buf, style...)
+	buf = append(buf, s...)
+	return append(buf, consoleReset...)
+}
+
+// consoleValue formats a field value, quoting it if it would be ambiguous
+// on the line.
+func consoleValue(value any) string {
+	s := fmt.Sprint(value)
+	if s == "" || strings.ContainsAny(s, " \t\r\n\"=") {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+// parseConsoleEnv parses the value of ConsoleEnv. It reports whether the
+// console is enabled, and at which level if one is given.
+func parseConsoleEnv(value string) (enabled bool, level logging.Level, levelSet bool, err error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	switch value {
+	case "", "0", "false", "off", "no":
+		return false, 0, false, nil
+	case "1", "true", "on", "yes":
+		return true, 0, false, nil
+	case "warn":
+		return true, logging.WarnLevel, true, nil
+	}
+	for level, name := range levelNames {
+		if name == value {
+			return true, level, true, nil
+		}
+	}
+	return false, 0, false, fmt.Errorf("invalid %s value %q", ConsoleEnv, value)
+}
@@ -0,0 +1,99 @@
+package influxlogger
+
+import (
+	"bytes"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/hadi77ir/go-logging"
+)
+
+func TestConsole(t *testing.T) {
+	var out bytes.Buffer
+	backend := &recordingBackend{}
+	writer := newTestWriter(t, "", 0, WithBackend(backend), WithLevel(logging.InfoLevel), WithConsole(&out, false))
+	if err := writer.Write(logging.InfoLevel, []any{"request served"}, logging.Fields{"status": 200, "path": "/a b", "empty": ""}); err != nil {
+		t.Fatal(err)
+	}
+	// entries below the level of the writer are neither printed nor written
+	if err := writer.Write(logging.DebugLevel, []any{"hidden"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	want := regexp.MustCompile(`^\d\d:\d\d:\d\d\.\d\d\d INFO  request served empty="" path="/a b" status=200\n$`)
+	if !want.MatchString(out.String()) {
+		t.Errorf("got %q", out.String())
+	}
+	if lines := backend.written(t); len(lines) != 1 {
+		t.Errorf("got %d lines written, want 1", len(lines))
+	}
+
+	out.Reset()
+	writer = newTestWriter(t, "", 0, WithBackend(&recordingBackend{}), WithConsole(&out, true))
+	if err := writer.Write(logging.ErrorLevel, []any{"failed"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := out.String(); !strings.Contains(got, "\x1b[31mERROR\x1b[0m failed") {
+		t.Errorf("got %q, want the level in red", got)
+	}
+
+	if err := optionsError(WithConsole(nil, false)); err == nil {
+		t.Error("expected a nil console writer to be rejected")
+	}
+}
+
+func TestConsoleFallbackFromEnv(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		set   bool
+		valid bool
+		// printed is the least severe level printed, if any is
+		printed logging.Level
+		enabled bool
+	}{
+		{"unset", "", false, true, 0, false},
+		{"off", "off", true, true, 0, false},
+		{"on", "on", true, true, logging.InfoLevel, true},
+		{"level", "debug", true, true, logging.DebugLevel, true},
+		{"warn", " WARN ", true, true, logging.WarnLevel, true},
+		{"invalid level", "verbose", true, false, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// unset by t.Setenv restoring it at the end of the test
+			t.Setenv(ConsoleEnv, tt.value)
+			if !tt.set {
+				_ = os.Unsetenv(ConsoleEnv)
+			}
+			t.Setenv("NO_COLOR", "1")
+			if !tt.valid {
+				if err := optionsError(WithConsoleFallbackFromEnv()); err == nil {
+					t.Errorf("expected %q to be rejected", tt.value)
+				}
+				return
+			}
+			// the environment overrides an explicit console
+			var explicit bytes.Buffer
+			writer := newTestWriter(t, "", 0, WithBackend(&recordingBackend{}), WithLevel(logging.InfoLevel),
+				WithConsole(&explicit, false), WithConsoleFallbackFromEnv())
+			if !tt.enabled {
+				if writer.console != nil {
+					t.Error("expected the console to be off")
+				}
+				return
+			}
+			if writer.console == nil || writer.console.out != os.Stdout || writer.console.color {
+				t.Fatalf("got console %+v, want standard output without color", writer.console)
+			}
+			if writer.console.level != tt.printed {
+				t.Errorf("got console level %v, want %v", writer.console.level, tt.printed)
+			}
+			// a console level below the writer's enables those entries
+			if got := writer.Enabled(logging.DebugLevel); got != (tt.printed == logging.DebugLevel) {
+				t.Errorf("got debug enabled %t", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+// Package echologging provides Echo middleware writing an access log entry
+// for every request through an influxlogger.LogWriter.
+package echologging
+
+import (
+	"time"
+
+	influxlogger "github.com/hadi77ir/go-influxlogger"
+	"github.com/labstack/echo/v4"
+)
+
+// Middleware returns middleware logging every request handled after it.
+// Errors returned by handlers are passed to the error handler of Echo
+// first, so the status they result in is logged.
+func Middleware(w *influxlogger.LogWriter) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+			err := next(c)
+			if err != nil {
+				c.Error(err)
+			}
+			req := c.Request()
+			res := c.Response()
+			influxlogger.LogHTTPRequest(w, influxlogger.HTTPRequest{
+				Method:     req.Method,
+				Path:       req.URL.Path,
+				Route:      c.Path(),
+				RemoteAddr: c.RealIP(),
+				UserAgent:  req.UserAgent(),
+				Status:     res.Status,
+				Size:       res.Size,
+				Latency:    time.Since(start),
+			})
+			// the error was handled, returning it would answer it twice
+			return nil
+		}
+	}
+}
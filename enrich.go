@@ -0,0 +1,97 @@
+package influxlogger
+
+import (
+	"maps"
+	"os"
+	"runtime/debug"
+
+	"github.com/hadi77ir/go-logging"
+)
+
+// Enricher adds fields to entries, such as where or by what build they
+// were logged.
+type Enricher interface {
+	// Enrich returns the fields to add to e. It must not modify e.
+	Enrich(e *Entry) logging.Fields
+}
+
+// EnricherFunc adapts a function to an Enricher.
+type EnricherFunc func(e *Entry) logging.Fields
+
+func (f EnricherFunc) Enrich(e *Entry) logging.Fields {
+	return f(e)
+}
+
+// enrich adds the fields of the writer's enrichers to e. Fields of the
+// entry take precedence, then those of per-entry enrichers, then cached
+// ones; among enrichers of a kind, the first registered wins. The fields
+// of e are shared with its logger, so they are copied rather than
+// modified.
+func (w *LogWriter) enrich(e *Entry) {
+	if len(w.enrichers) == 0 && len(w.cachedFields) == 0 {
+		return
+	}
+	fields := maps.Clone(w.cachedFields)
+	if fields == nil {
+		fields = make(logging.Fields, len(e.Fields))
+	}
+	for i := len(w.enrichers) - 1; i >= 0; i-- {
+		maps.Copy(fields, w.enrichers[i].Enrich(e))
+	}
+	maps.Copy(fields, e.Fields)
+	e.Fields = fields
+}
+
+// cacheEnrichers runs the enrichers whose fields don't change over the life
+// of the writer, keeping their fields for every entry.
+func (w *LogWriter) cacheEnrichers(enrichers []Enricher) {
+	if len(enrichers) == 0 {
+		return
+	}
+	w.cachedFields = logging.Fields{}
+	for i := len(enrichers) - 1; i >= 0; i-- {
+		maps.Copy(w.cachedFields, enrichers[i].Enrich(&Entry{}))
+	}
+}
+
+// BuildInfoEnricher adds the module path and version of the main package,
+// and the VCS revision it was built from, when the binary records them.
+// Its fields don't change, so it is meant for WithCachedEnricher.
+func BuildInfoEnricher() Enricher {
+	return EnricherFunc(func(*Entry) logging.Fields {
+		info, ok := debug.ReadBuildInfo()
+		if !ok {
+			return nil
+		}
+		fields := logging.Fields{
+			"build_path":    info.Main.Path,
+			"build_version": info.Main.Version,
+		}
+		for _, setting := range info.Settings {
+			if setting.Key == "vcs.revision" {
+				fields["build_revision"] = setting.Value
+			}
+		}
+		return fields
+	})
+}
+
+// KubernetesEnricher adds the pod, namespace and node found in the
+// POD_NAME, POD_NAMESPACE and NODE_NAME environment variables, which are
+// commonly set through the Kubernetes downward API. Unset variables are
+// left out. Its fields don't change, so it is meant for WithCachedEnricher.
+func KubernetesEnricher() Enricher {
+	return EnricherFunc(func(*Entry) logging.Fields {
+		fields := logging.Fields{}
+		for field, env := range map[string]string{
+			"k8s_pod":       "POD_NAME",
+			"k8s_namespace": "POD_NAMESPACE",
+			"k8s_node":      "NODE_NAME",
+		} {
+			if value := os.Getenv(env); value != "" {
+				fields[field] = value
+			}
+		}
+		return fields
+	})
+}
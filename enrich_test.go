@@ -0,0 +1,161 @@
+package influxlogger
+
+import (
+	"io"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync/atomic"
+	"testing"
+
+	"github.com/hadi77ir/go-logging"
+)
+
+func TestEnrichers(t *testing.T) {
+	tests := []struct {
+		name   string
+		option func(enricher Enricher) Option
+		// calls is the number of times the enricher is called for a
+		// writer of three entries, and idleCalls for one without entries
+		calls     int32
+		idleCalls int32
+		region    string
+		seen      []any
+	}{
+		// enrichers are run for every entry and see it, cached ones once
+		// per writer with an empty entry; per-entry enrichers win
+		{"per entry", WithEnricher, 3, 0, "eu", []any{"a", "b", "c"}},
+		{"cached", WithCachedEnricher, 1, 1, "us", []any{"", "", ""}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			enricher := EnricherFunc(func(e *Entry) logging.Fields {
+				calls.Add(1)
+				return logging.Fields{"region": "eu", "service": "enriched", "seen": e.Message}
+			})
+			lines := writtenLines(t, func(w *LogWriter) {
+				for _, message := range []string{"a", "b", "c"} {
+					if err := w.Write(logging.InfoLevel, []any{message}, logging.Fields{"service": "api"}); err != nil {
+						t.Fatal(err)
+					}
+				}
+			}, tt.option(enricher), WithEnricher(EnricherFunc(func(*Entry) logging.Fields {
+				return logging.Fields{"region": "us"}
+			})))
+			if got := calls.Load(); got != tt.calls {
+				t.Errorf("enricher called %d times, want %d", got, tt.calls)
+			}
+			var seen []any
+			for _, l := range lines {
+				// fields of the entry win over enriched ones
+				if l.fields["fields.service"] != "api" {
+					t.Errorf("got service %#v, want api", l.fields["fields.service"])
+				}
+				if l.fields["fields.region"] != tt.region {
+					t.Errorf("got region %#v, want %s", l.fields["fields.region"], tt.region)
+				}
+				seen = append(seen, l.fields["fields.seen"])
+			}
+			if !slices.Equal(seen, tt.seen) {
+				t.Errorf("enricher saw %q, want %q", seen, tt.seen)
+			}
+			calls.Store(0)
+			newTestWriter(t, "", 0, WithBackend(&recordingBackend{}), tt.option(enricher))
+			if got := calls.Load(); got != tt.idleCalls {
+				t.Errorf("enricher called %d times for a writer without entries, want %d", got, tt.idleCalls)
+			}
+		})
+	}
+	for _, opt := range []Option{WithEnricher(nil), WithCachedEnricher(nil)} {
+		if err := optionsError(opt); err == nil {
+			t.Error("expected a nil enricher to be rejected")
+		}
+	}
+}
+
+func TestCloudEnricher(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      map[string]string
+		metadata map[string]string
+		want     logging.Fields
+		requests int32
+	}{
+		{
+			name: "aws",
+			metadata: map[string]string{
+				"PUT /latest/api/token":                  "token",
+				"GET /latest/meta-data/placement/region": "eu-west-1",
+				"GET /latest/meta-data/instance-id":      "i-0123",
+			},
+			want:     logging.Fields{"cloud_provider": "aws", "cloud_region": "eu-west-1", "cloud_instance": "i-0123"},
+			requests: 3,
+		},
+		{
+			name: "gcp",
+			metadata: map[string]string{
+				"GET /computeMetadata/v1/instance/zone": "projects/1/zones/europe-west1-b",
+				"GET /computeMetadata/v1/instance/id":   "4567",
+			},
+			want:     logging.Fields{"cloud_provider": "gcp", "cloud_region": "europe-west1", "cloud_instance": "4567"},
+			requests: 3,
+		},
+		{
+			name: "azure",
+			metadata: map[string]string{
+				"GET /metadata/instance/compute": `{"location":"westeurope","vmId":"vm-89"}`,
+			},
+			want:     logging.Fields{"cloud_provider": "azure", "cloud_region": "westeurope", "cloud_instance": "vm-89"},
+			requests: 3,
+		},
+		{
+			name:     "environment",
+			env:      map[string]string{"CLOUD_PROVIDER": "onprem", "CLOUD_REGION": "dc1", "CLOUD_INSTANCE": "rack-4"},
+			want:     logging.Fields{"cloud_provider": "onprem", "cloud_region": "dc1", "cloud_instance": "rack-4"},
+			requests: 0,
+		},
+		{
+			name: "environment first",
+			env:  map[string]string{"CLOUD_PROVIDER": "gcp", "CLOUD_REGION": "us-east1"},
+			metadata: map[string]string{
+				"GET /computeMetadata/v1/instance/zone": "projects/1/zones/europe-west1-b",
+				"GET /computeMetadata/v1/instance/id":   "4567",
+			},
+			want: logging.Fields{"cloud_provider": "gcp", "cloud_region": "us-east1", "cloud_instance": "4567"},
+			// only the metadata server of the provider set is asked
+			requests: 2,
+		},
+		{
+			name:     "off the cloud",
+			want:     logging.Fields{},
+			requests: 3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, env := range []string{"CLOUD_PROVIDER", "CLOUD_REGION", "CLOUD_INSTANCE"} {
+				t.Setenv(env, tt.env[env])
+			}
+			var requests atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+				requests.Add(1)
+				body, ok := tt.metadata[r.Method+" "+r.URL.Path]
+				if !ok {
+					rw.WriteHeader(http.StatusNotFound)
+					return
+				}
+				_, _ = io.WriteString(rw, body)
+			}))
+			t.Cleanup(server.Close)
+			enricher := &cloudEnricher{base: server.URL, client: server.Client()}
+			if got := enricher.Enrich(&Entry{}); !maps.Equal(got, tt.want) {
+				t.Errorf("got fields %v, want %v", got, tt.want)
+			}
+			if got := requests.Load(); got != tt.requests {
+				t.Errorf("got %d metadata requests, want %d", got, tt.requests)
+			}
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package influxlogger
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// BytesEncoding selects how []byte field values that aren't valid UTF-8
+// are written.
+type BytesEncoding int
+
+const (
+	// BytesBase64 writes them as standard base64. It is the default.
+	BytesBase64 BytesEncoding = iota
+	// BytesHex writes them as lowercase hex.
+	BytesHex
+)
+
+// DurationEncoding selects how time.Duration field values are written.
+type DurationEncoding int
+
+const (
+	// DurationSeconds writes durations as a float of seconds. It is the
+	// default.
+	DurationSeconds DurationEncoding = iota
+	// DurationString writes durations in the form of time.Duration.String,
+	// e.g. "1.5s".
+	DurationString
+	// DurationNanoseconds writes durations as an integer of nanoseconds.
+	DurationNanoseconds
+)
+
+// InvalidValuePolicy selects what happens to field values line protocol
+// can't represent: nil, NaN and infinities.
+type InvalidValuePolicy int
+
+const (
+	// InvalidValueDrop leaves the field out. It is the default.
+	InvalidValueDrop InvalidValuePolicy = iota
+	// InvalidValueSentinel writes the field as the string "nil", "NaN",
+	// "+Inf" or "-Inf".
+	InvalidValueSentinel
+	// InvalidValueError leaves the field out and reports it to the error
+	// handler.
+	InvalidValueError
+)
+
+// truncatedSuffix marks values cut short by a size cap.
+const truncatedSuffix = "..."
+
+// emitField calls fn with the converted value of the entry field key and,
+// if requested, with the type name of values formatted by their own
+// methods.
+func (w *LogWriter) emitField(key string, value any, fn func(key string, value any) error) error {
+	key = w.sanitizeUTF8(key)
+	if sentinel, ok := invalidValue(value); ok {
+		switch w.invalidValues {
+		case InvalidValueSentinel:
+			return fn(key, sentinel)
+		case InvalidValueError:
+			w.handleError(fmt.Errorf("field %q has invalid value %s", key, sentinel))
+		}
+		return nil
+	}
+	converted := w.convertField(value)
+	if str, ok := converted.(string); ok {
+		converted = w.sanitizeUTF8(str)
+	}
+	if err := fn(key, converted); err != nil {
+		return err
+	}
+	if !w.fieldTypeNames {
+		return nil
+	}
+	switch value.(type) {
+	case time.Time, time.Duration:
+	case error, fmt.Stringer:
+		return fn(key+"_type", fmt.Sprintf("%T", value))
+	}
+	return nil
+}
+
+// convertField applies the writer's encoding policies to the value of an
+// entry field.
+func (w *LogWriter) convertField(value any) any {
+	switch v := value.(type) {
+	case []byte:
+		return w.encodeBytes(v)
+	case time.Time:
+		return w.timeFieldFormat(v)
+	case time.Duration:
+		return w.encodeDuration(v)
+	case error:
+		return callString("Error", v.Error)
+	case fmt.Stringer:
+		return callString("String", v.String)
+	}
+	return value
+}
+
+// callString calls method, turning a panic into a description of it the
+// way fmt does, so a faulty value can't crash the logging goroutine.
+func callString(name string, method func() string) (s string) {
+	defer func() {
+		if r := recover(); r != nil {
+			s = fmt.Sprintf("%%!v(PANIC=%s method: %v)", name, r)
+		}
+	}()
+	return method()
+}
+
+func (w *LogWriter) encodeDuration(d time.Duration) any {
+	switch w.durationEncoding {
+	case DurationString:
+		return d.String()
+	case DurationNanoseconds:
+		return d.Nanoseconds()
+	}
+	return d.Seconds()
+}
+
+// encodeBytes writes b as a string if it is valid UTF-8, or in the
+// configured encoding otherwise, after capping it to maxBytes.
+func (w *LogWriter) encodeBytes(b []byte) string {
+	truncated := w.maxBytes > 0 && len(b) > w.maxBytes
+	if truncated {
+		valid := utf8.Valid(b)
+		b = b[:w.maxBytes]
+		// don't let the cap turn valid text into an invalid sequence
+		for valid && len(b) > 0 && !utf8.Valid(b) {
+			b = b[:len(b)-1]
+		}
+	}
+	var s string
+	switch {
+	case utf8.Valid(b):
+		s = string(b)
+	case w.bytesEncoding == BytesHex:
+		s = hex.EncodeToString(b)
+	default:
+		s = base64.StdEncoding.EncodeToString(b)
+	}
+	if truncated {
+		s += truncatedSuffix
+	}
+	return s
+}
+
+// invalidValue reports whether value can't be represented in line protocol,
+// along with the sentinel standing for it.
+func invalidValue(value any) (string, bool) {
+	var f float64
+	switch v := value.(type) {
+	case nil:
+		return "nil", true
+	case float64:
+		f = v
+	case float32:
+		f = float64(v)
+	default:
+		if rv := reflect.ValueOf(value); rv.Kind() == reflect.Pointer && rv.IsNil() {
+			return "nil", true
+		}
+		return "", false
+	}
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return strconv.FormatFloat(f, 'g', -1, 64), true
+	}
+	return "", false
+}
+
+// sanitizeUTF8 replaces every run of invalid UTF-8 in s, which line
+// protocol can't carry, with the writer's replacement.
+func (w *LogWriter) sanitizeUTF8(s string) string {
+	if utf8.ValidString(s) {
+		return s
+	}
+	return strings.ToValidUTF8(s, w.utf8Replacement)
+}
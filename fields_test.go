@@ -0,0 +1,167 @@
+package influxlogger
+
+import (
+	"errors"
+	"math"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hadi77ir/go-logging"
+)
+
+func TestBytesEncoding(t *testing.T) {
+	tests := []struct {
+		name  string
+		opts  []Option
+		value []byte
+		want  string
+	}{
+		{"text", nil, []byte("hello"), "hello"},
+		{"base64", nil, []byte{0xff, 0x00, 0x01}, "/wAB"},
+		{"hex", []Option{WithBytesEncoding(BytesHex, 0)}, []byte{0xff, 0x00, 0x01}, "ff0001"},
+		{"capped text", []Option{WithBytesEncoding(BytesBase64, 3)}, []byte("hello"), "hel..."},
+		{"capped binary", []Option{WithBytesEncoding(BytesHex, 2)}, []byte{0xff, 0x00, 0x01}, "ff00..."},
+		{"cap within a character", []Option{WithBytesEncoding(BytesBase64, 2)}, []byte("aé"), "a..."},
+		{"under the cap", []Option{WithBytesEncoding(BytesBase64, 5)}, []byte("hello"), "hello"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := writtenLine(t, logging.InfoLevel, "hello", logging.Fields{"body": tt.value}, tt.opts...)
+			if got := l.fields["fields.body"]; got != tt.want {
+				t.Errorf("got %#v, want %q", got, tt.want)
+			}
+		})
+	}
+	for _, opt := range []Option{WithBytesEncoding(BytesHex+1, 0), WithBytesEncoding(BytesBase64, -1)} {
+		if err := optionsError(opt); err == nil {
+			t.Error("expected an invalid bytes encoding to be rejected")
+		}
+	}
+}
+
+func TestTimeFields(t *testing.T) {
+	at := time.Date(2024, 5, 1, 12, 30, 0, 500, time.FixedZone("CEST", 2*60*60))
+	tests := []struct {
+		name  string
+		opts  []Option
+		value any
+		want  any
+	}{
+		{"time", nil, at, "2024-05-01T10:30:00.0000005Z"},
+		{"time format", []Option{WithTimeFieldFormat(TimestampUnixMilli)}, at, at.UnixMilli()},
+		{"time layout", []Option{WithTimeFieldFormat(TimestampLayout(time.DateOnly))}, at, "2024-05-01"},
+		{"duration", nil, 1500 * time.Millisecond, 1.5},
+		{"duration string", []Option{WithDurationEncoding(DurationString)}, 1500 * time.Millisecond, "1.5s"},
+		{"duration nanoseconds", []Option{WithDurationEncoding(DurationNanoseconds)}, 1500 * time.Millisecond, int64(1500000000)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// type names are never added for times and durations
+			opts := append(tt.opts, WithFieldTypeNames())
+			l := writtenLine(t, logging.InfoLevel, "hello", logging.Fields{"value": tt.value}, opts...)
+			if got := l.fields["fields.value"]; got != tt.want {
+				t.Errorf("got %#v, want %#v", got, tt.want)
+			}
+			if got, ok := l.fields["fields.value_type"]; ok {
+				t.Errorf("got type name %#v", got)
+			}
+		})
+	}
+	if err := optionsError(WithTimeFieldFormat(nil)); err == nil {
+		t.Error("expected a nil time field format to be rejected")
+	}
+	if err := optionsError(WithDurationEncoding(DurationNanoseconds + 1)); err == nil {
+		t.Error("expected an invalid duration encoding to be rejected")
+	}
+}
+
+// panicStringer panics when formatted.
+type panicStringer struct{}
+
+func (panicStringer) String() string {
+	panic("boom")
+}
+
+func TestErrorFields(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    any
+		want     string
+		typeName string
+	}{
+		{"error", errors.New("failed"), "failed", "*errors.errorString"},
+		{"stringer", net.IPv4(10, 0, 0, 1), "10.0.0.1", "net.IP"},
+		{"panicking stringer", panicStringer{}, "%!v(PANIC=String method: boom)", "influxlogger.panicStringer"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := writtenLine(t, logging.InfoLevel, "hello", logging.Fields{"value": tt.value})
+			if got := l.fields["fields.value"]; got != tt.want {
+				t.Errorf("got %#v, want %q", got, tt.want)
+			}
+			if got, ok := l.fields["fields.value_type"]; ok {
+				t.Errorf("got type name %#v without WithFieldTypeNames", got)
+			}
+			l = writtenLine(t, logging.InfoLevel, "hello", logging.Fields{"value": tt.value}, WithFieldTypeNames())
+			if got := l.fields["fields.value_type"]; got != tt.typeName {
+				t.Errorf("got type name %#v, want %q", got, tt.typeName)
+			}
+		})
+	}
+}
+
+func TestInvalidValuePolicy(t *testing.T) {
+	var nilError error
+	var nilPointer *int
+	fields := logging.Fields{
+		"nil":      nilError,
+		"pointer":  nilPointer,
+		"nan":      math.NaN(),
+		"inf":      math.Inf(1),
+		"neginf":   float32(math.Inf(-1)),
+		"valid":    1.5,
+		"zero_int": 0,
+	}
+	sentinels := map[string]any{"nil": "nil", "pointer": "nil", "nan": "NaN", "inf": "+Inf", "neginf": "-Inf"}
+	tests := []struct {
+		name   string
+		policy InvalidValuePolicy
+		want   map[string]any
+		errors int
+	}{
+		{"drop", InvalidValueDrop, nil, 0},
+		{"sentinel", InvalidValueSentinel, sentinels, 0},
+		{"error", InvalidValueError, nil, len(sentinels)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var errs []error
+			l := writtenLine(t, logging.InfoLevel, "hello", fields,
+				WithInvalidValuePolicy(tt.policy),
+				WithErrorHandler(func(err error) { errs = append(errs, err) }))
+			if l.fields["fields.valid"] != 1.5 || l.fields["fields.zero_int"] != int64(0) {
+				t.Errorf("got valid fields %v", l.fields)
+			}
+			for key := range sentinels {
+				got, ok := l.fields["fields."+key]
+				want, wantOK := tt.want[key]
+				if ok != wantOK || got != want {
+					t.Errorf("got %s %#v, want %#v", key, got, want)
+				}
+			}
+			if len(errs) != tt.errors {
+				t.Errorf("got errors %v, want %d", errs, tt.errors)
+			}
+			for _, err := range errs {
+				if !strings.Contains(err.Error(), "invalid value") {
+					t.Errorf("got error %v", err)
+				}
+			}
+		})
+	}
+	if err := optionsError(WithInvalidValuePolicy(InvalidValueError + 1)); err == nil {
+		t.Error("expected an invalid policy to be rejected")
+	}
+}
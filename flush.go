@@ -0,0 +1,162 @@
+package influxlogger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+)
+
+// run flushes the buffer every flushInterval, and additionally once no new
+// entries have arrived for idleFlush, or urgentInterval after an urgent
+// entry arrived, until the writer is closed.
+func (w *LogWriter) run() {
+	defer close(w.stopped)
+	tick := time.NewTimer(w.nextFlush())
+	defer tick.Stop()
+	var idle *time.Timer
+	var idleC <-chan time.Time
+	if w.idleFlush > 0 {
+		idle = time.NewTimer(w.idleFlush)
+		defer idle.Stop()
+		idleC = idle.C
+	}
+	var urgent *time.Timer
+	var urgentC <-chan time.Time
+	if w.urgentFlush != nil {
+		urgent = time.NewTimer(w.urgentInterval)
+		urgent.Stop()
+		defer urgent.Stop()
+	}
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-tick.C:
+			w.flushInBackground()
+			tick.Reset(w.nextFlush())
+		case <-w.activity:
+			idle.Reset(w.idleFlush)
+		case <-idleC:
+			w.flushInBackground()
+		case <-w.urgentFlush:
+			// later urgent entries are written by the flush already due
+			if urgentC == nil {
+				urgent.Reset(w.urgentInterval)
+				urgentC = urgent.C
+			}
+		case <-urgentC:
+			urgentC = nil
+			w.flushInBackground()
+		}
+	}
+}
+
+// nextFlush returns the delay until the next periodic flush, spread by a
+// random jitter so that instances started together don't flush together.
+func (w *LogWriter) nextFlush() time.Duration {
+	if w.flushJitter <= 0 {
+		return w.flushInterval
+	}
+	return w.flushInterval + rand.N(w.flushJitter)
+}
+
+// notifyFlush tells the flush loop that e was buffered. An urgent entry is
+// flushed right away if urgentInterval is zero. It must be called with
+// flushMutex held.
+func (w *LogWriter) notifyFlush(ctx context.Context, e *Entry) error {
+	if w.idleFlush > 0 {
+		select {
+		case w.activity <- struct{}{}:
+		default:
+		}
+	}
+	if w.urgentFlush == nil || levelRank[e.Level] < levelRank[w.urgentLevel] {
+		return nil
+	}
+	if w.urgentInterval == 0 {
+		return w.flushBuffer(ctx)
+	}
+	select {
+	case w.urgentFlush <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+func (w *LogWriter) flushInBackground() {
+	if err := w.Flush(w.ctx); err != nil {
+		w.handleError(err)
+	}
+}
+
+// handleError reports an error that has no caller to be returned to.
+func (w *LogWriter) handleError(err error) {
+	if w.errorHandler != nil {
+		w.errorHandler(err)
+	}
+}
+
+// recoverPanic recovers from a panic while writing, such as one raised by a
+// faulty field value or by the client, and reports it to the error handler.
+// If err isn't nil, the panic is also stored in it. It must be deferred.
+func (w *LogWriter) recoverPanic(err *error) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	panicErr := w.panicError(r)
+	if err != nil {
+		*err = panicErr
+	}
+}
+
+// panicError reports a panic recovered while writing to the error handler,
+// and returns it as an error.
+func (w *LogWriter) panicError(r any) error {
+	err := fmt.Errorf("recovered from panic while writing: %v", r)
+	w.handleError(err)
+	return err
+}
+
+// Flush writes all buffered entries.
+func (w *LogWriter) Flush(ctx context.Context) error {
+	if w.buffer == nil && w.batcher == nil {
+		return nil
+	}
+	w.flushMutex.Lock()
+	defer w.flushMutex.Unlock()
+	return w.flushBuffer(ctx)
+}
+
+// Shutdown cancels writes still in flight, stops the periodic flush and
+// writes the remaining buffered entries within ctx before closing the
+// backend. The writer must not be used after Shutdown.
+func (w *LogWriter) Shutdown(ctx context.Context) error {
+	err := ErrClosed
+	w.closeOnce.Do(func() {
+		w.cancel()
+		close(w.done)
+		<-w.stopped
+		if w.hooksStopped != nil {
+			<-w.hooksStopped
+		}
+		if w.levelMetrics != nil {
+			<-w.levelMetrics.stopped
+		}
+		if w.heartbeat != nil {
+			<-w.heartbeat.stopped
+		}
+		// the final flush is the last chance to write, even while paused
+		w.pausedUntil.Store(0)
+		err = errors.Join(w.Flush(ctx), w.backend.Close())
+	})
+	return err
+}
+
+// Close is like Shutdown, but waits for the remaining entries to be written
+// without a deadline.
+func (w *LogWriter) Close() error {
+	return w.Shutdown(context.Background())
+}
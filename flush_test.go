@@ -0,0 +1,61 @@
+package influxlogger
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hadi77ir/go-logging"
+)
+
+func TestLevelFlushInterval(t *testing.T) {
+	tests := []struct {
+		name string
+		opt  Option
+		// wait is how long an error may take to be written
+		wait time.Duration
+	}{
+		{"interval", WithLevelFlushInterval(logging.ErrorLevel, 10*time.Millisecond), time.Second},
+		{"immediate", WithLevelImmediateFlush(logging.ErrorLevel), 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := &recordingBackend{}
+			writer := newTestWriter(t, "", 10, WithBackend(backend), tt.opt)
+			writeMessages(t, writer, "info")
+			time.Sleep(30 * time.Millisecond)
+			if lines := backend.written(t); len(lines) != 0 {
+				t.Fatalf("got %d lines before the flush interval", len(lines))
+			}
+			if err := writer.Write(logging.ErrorLevel, []any{"error"}, nil); err != nil {
+				t.Fatal(err)
+			}
+			deadline := time.Now().Add(tt.wait)
+			lines := backend.written(t)
+			for len(lines) < 2 && time.Now().Before(deadline) {
+				time.Sleep(time.Millisecond)
+				lines = backend.written(t)
+			}
+			// the buffered entries are flushed along with the error
+			if len(lines) != 2 {
+				t.Errorf("got %d lines, want 2", len(lines))
+			}
+		})
+	}
+}
+
+func TestLevelFlushIntervalInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		opt  Option
+	}{
+		{"zero interval", WithLevelFlushInterval(logging.ErrorLevel, 0)},
+		{"negative interval", WithLevelFlushInterval(logging.ErrorLevel, -time.Second)},
+		{"level", WithLevelFlushInterval(logging.Level(100), time.Second)},
+		{"immediate level", WithLevelImmediateFlush(logging.Level(100))},
+	}
+	for _, tt := range tests {
+		if err := optionsError(tt.opt); err == nil {
+			t.Errorf("expected an invalid %s to be rejected", tt.name)
+		}
+	}
+}
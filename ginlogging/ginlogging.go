@@ -0,0 +1,28 @@
+// Package ginlogging provides Gin middleware writing an access log entry
+// for every request through an influxlogger.LogWriter.
+package ginlogging
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	influxlogger "github.com/hadi77ir/go-influxlogger"
+)
+
+// Middleware returns middleware logging every request handled after it.
+func Middleware(w *influxlogger.LogWriter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+		influxlogger.LogHTTPRequest(w, influxlogger.HTTPRequest{
+			Method:     c.Request.Method,
+			Path:       c.Request.URL.Path,
+			Route:      c.FullPath(),
+			RemoteAddr: c.ClientIP(),
+			UserAgent:  c.Request.UserAgent(),
+			Status:     c.Writer.Status(),
+			Size:       int64(max(c.Writer.Size(), 0)),
+			Latency:    time.Since(start),
+		})
+	}
+}
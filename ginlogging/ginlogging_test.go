@@ -0,0 +1,118 @@
+package ginlogging
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	influxlogger "github.com/hadi77ir/go-influxlogger"
+	"github.com/influxdata/line-protocol/v2/lineprotocol"
+)
+
+// recordingBackend keeps the line protocol written to it.
+type recordingBackend struct {
+	mutex sync.Mutex
+	lines []byte
+}
+
+func (b *recordingBackend) WriteLineProtocol(ctx context.Context, lines []byte) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.lines = append(b.lines, lines...)
+	return nil
+}
+
+func (b *recordingBackend) Close() error {
+	return nil
+}
+
+// points returns the fields of the points written so far.
+func (b *recordingBackend) points(t *testing.T) []map[string]any {
+	t.Helper()
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	var points []map[string]any
+	dec := lineprotocol.NewDecoderWithBytes(b.lines)
+	for dec.Next() {
+		fields := map[string]any{}
+		for {
+			key, value, err := dec.NextField()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if key == nil {
+				break
+			}
+			fields[string(key)] = value.Interface()
+		}
+		points = append(points, fields)
+	}
+	return points
+}
+
+func TestMiddleware(t *testing.T) {
+	const delay = 5 * time.Millisecond
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		status int
+		route  string
+	}{
+		{"routed", http.MethodGet, "/users/42", http.StatusCreated, "/users/:id"},
+		{"failed", http.MethodPost, "/fail", http.StatusInternalServerError, "/fail"},
+		{"not found", http.MethodGet, "/missing", http.StatusNotFound, ""},
+	}
+	gin.SetMode(gin.TestMode)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := &recordingBackend{}
+			writer, err := influxlogger.NewLogWriter("", "test", "localhost", "1", 0, 0, influxlogger.WithBackend(backend))
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { _ = writer.Close() })
+			router := gin.New()
+			router.Use(Middleware(writer))
+			router.GET("/users/:id", func(c *gin.Context) {
+				time.Sleep(delay)
+				c.String(http.StatusCreated, "created")
+			})
+			router.POST("/fail", func(c *gin.Context) {
+				time.Sleep(delay)
+				c.AbortWithStatus(http.StatusInternalServerError)
+			})
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, nil))
+			if rec.Code != tt.status {
+				t.Fatalf("got status %d, want %d", rec.Code, tt.status)
+			}
+			points := backend.points(t)
+			if len(points) != 1 {
+				t.Fatalf("got %d points, want 1", len(points))
+			}
+			fields := points[0]
+			want := map[string]any{
+				"fields.http_method": tt.method,
+				"fields.http_path":   tt.path,
+				"fields.http_status": int64(tt.status),
+			}
+			for key, value := range want {
+				if fields[key] != value {
+					t.Errorf("got %s %#v, want %#v", key, fields[key], value)
+				}
+			}
+			if tt.route != "" && fields["fields.http_route"] != tt.route {
+				t.Errorf("got route %#v, want %s", fields["fields.http_route"], tt.route)
+			}
+			latency, ok := fields["fields.latency"].(float64)
+			if !ok || (tt.route != "" && latency < delay.Seconds()) {
+				t.Errorf("got latency %#v, want at least %v", fields["fields.latency"], delay)
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+// Package grpclogging provides gRPC interceptors writing an access log
+// entry for every call through an influxlogger.LogWriter.
+package grpclogging
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	influxlogger "github.com/hadi77ir/go-influxlogger"
+	"github.com/hadi77ir/go-logging"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// CodeLevel returns the level a call ending with code is logged at:
+// successful calls at info, errors caused by the caller at warning, and
+// errors of the server at error.
+func CodeLevel(code codes.Code) logging.Level {
+	switch code {
+	case codes.OK:
+		return logging.InfoLevel
+	case codes.Canceled, codes.InvalidArgument, codes.NotFound, codes.AlreadyExists,
+		codes.PermissionDenied, codes.Unauthenticated, codes.ResourceExhausted,
+		codes.FailedPrecondition, codes.Aborted, codes.OutOfRange:
+		return logging.WarnLevel
+	default:
+		return logging.ErrorLevel
+	}
+}
+
+// logCall writes the entry of a finished call. kind is either "server" or
+// "client", and callType either "unary" or "stream".
+func logCall(ctx context.Context, w *influxlogger.LogWriter, kind, callType, method string, start time.Time, err error) {
+	code := status.Code(err)
+	level := CodeLevel(code)
+	if !w.Enabled(level) {
+		return
+	}
+	fields := logging.Fields{
+		"grpc_kind":   kind,
+		"grpc_type":   callType,
+		"grpc_method": method,
+		"grpc_code":   code.String(),
+		"latency":     time.Since(start),
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		fields["peer"] = p.Addr.String()
+	}
+	if err != nil {
+		fields["error"] = status.Convert(err).Message()
+	}
+	_ = w.Write(level, []any{method, " ", code.String()}, fields)
+}
+
+// UnaryServerInterceptor logs every unary call served.
+func UnaryServerInterceptor(w *influxlogger.LogWriter) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		logCall(ctx, w, "server", "unary", info.FullMethod, start, err)
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor logs every streaming call served, once the
+// handler returns.
+func StreamServerInterceptor(w *influxlogger.LogWriter) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, ss)
+		logCall(ss.Context(), w, "server", "stream", info.FullMethod, start, err)
+		return err
+	}
+}
+
+// UnaryClientInterceptor logs every unary call made. The peer is only
+// known if the call is made with the grpc.Peer call option.
+func UnaryClientInterceptor(w *influxlogger.LogWriter) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		start := time.Now()
+		var p peer.Peer
+		err := invoker(ctx, method, req, reply, cc, append(opts, grpc.Peer(&p))...)
+		logCall(peer.NewContext(ctx, &p), w, "client", "unary", method, start, err)
+		return err
+	}
+}
+
+// StreamClientInterceptor logs every streaming call made, once the stream
+// ends with an error or with io.EOF from RecvMsg, or, for calls where the
+// server sends a single response, once that response is received.
+func StreamClientInterceptor(w *influxlogger.LogWriter) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		start := time.Now()
+		p := &peer.Peer{}
+		stream, err := streamer(ctx, desc, cc, method, append(opts, grpc.Peer(p))...)
+		if err != nil {
+			logCall(peer.NewContext(ctx, p), w, "client", "stream", method, start, err)
+			return nil, err
+		}
+		return &clientStream{
+			ClientStream:  stream,
+			writer:        w,
+			method:        method,
+			start:         start,
+			peer:          p,
+			serverStreams: desc.ServerStreams,
+		}, nil
+	}
+}
+
+// clientStream logs its call the first time RecvMsg fails or, if the server
+// doesn't stream, once RecvMsg returns its single response.
+type clientStream struct {
+	grpc.ClientStream
+	writer *influxlogger.LogWriter
+	method string
+	start  time.Time
+	// peer is filled in by gRPC once the stream is done
+	peer          *peer.Peer
+	serverStreams bool
+	once          sync.Once
+}
+
+func (s *clientStream) RecvMsg(m any) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err == nil && s.serverStreams {
+		return nil
+	}
+	s.once.Do(func() {
+		callErr := err
+		if errors.Is(err, io.EOF) {
+			callErr = nil
+		}
+		logCall(peer.NewContext(s.Context(), s.peer), s.writer, "client", "stream", s.method, s.start, callErr)
+	})
+	return err
+}
@@ -0,0 +1,225 @@
+package grpclogging
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	influxlogger "github.com/hadi77ir/go-influxlogger"
+	"github.com/hadi77ir/go-logging"
+	"github.com/influxdata/line-protocol/v2/lineprotocol"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// recordingBackend keeps the line protocol written to it.
+type recordingBackend struct {
+	mutex sync.Mutex
+	lines []byte
+}
+
+func (b *recordingBackend) WriteLineProtocol(ctx context.Context, lines []byte) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.lines = append(b.lines, lines...)
+	return nil
+}
+
+func (b *recordingBackend) Close() error {
+	return nil
+}
+
+// call is a logged call, decoded from line protocol.
+type call struct {
+	severity string
+	fields   map[string]any
+}
+
+// calls returns the calls logged so far by kind.
+func (b *recordingBackend) calls(t *testing.T) map[string]call {
+	t.Helper()
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	calls := map[string]call{}
+	dec := lineprotocol.NewDecoderWithBytes(b.lines)
+	for dec.Next() {
+		c := call{fields: map[string]any{}}
+		if _, err := dec.Measurement(); err != nil {
+			t.Fatal(err)
+		}
+		for {
+			key, value, err := dec.NextTag()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if key == nil {
+				break
+			}
+			if string(key) == "severity" {
+				c.severity = string(value)
+			}
+		}
+		for {
+			key, value, err := dec.NextField()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if key == nil {
+				break
+			}
+			c.fields[string(key)] = value.Interface()
+		}
+		calls[c.fields["fields.grpc_kind"].(string)] = c
+	}
+	return calls
+}
+
+// waitCalls waits for n calls to be logged, as the server logs its side
+// of a call after the client has seen it end.
+func (b *recordingBackend) waitCalls(t *testing.T, n int) map[string]call {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		calls := b.calls(t)
+		if len(calls) >= n || time.Now().After(deadline) {
+			return calls
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// newHealthClient serves the health service over an in-memory connection
+// and returns a client for it, with the interceptors of both sides logging
+// to the returned backend.
+func newHealthClient(t *testing.T, health *health.Server) (healthpb.HealthClient, *recordingBackend) {
+	t.Helper()
+	backend := &recordingBackend{}
+	writer, err := influxlogger.NewLogWriter("", "test", "localhost", "1", 0, 0, influxlogger.WithBackend(backend))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = writer.Close() })
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer(
+		grpc.UnaryInterceptor(UnaryServerInterceptor(writer)),
+		grpc.StreamInterceptor(StreamServerInterceptor(writer)))
+	healthpb.RegisterHealthServer(server, health)
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(server.Stop)
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithUnaryInterceptor(UnaryClientInterceptor(writer)),
+		grpc.WithStreamInterceptor(StreamClientInterceptor(writer)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return healthpb.NewHealthClient(conn), backend
+}
+
+// checkCalls checks that both sides logged a call of callType to method,
+// ending with code.
+func checkCalls(t *testing.T, calls map[string]call, callType, method string, code codes.Code, severity string) {
+	t.Helper()
+	for _, kind := range []string{"server", "client"} {
+		c, ok := calls[kind]
+		if !ok {
+			t.Errorf("no %s call logged", kind)
+			continue
+		}
+		if c.severity != severity {
+			t.Errorf("got %s severity %q, want %q", kind, c.severity, severity)
+		}
+		want := map[string]any{
+			"fields.grpc_type":   callType,
+			"fields.grpc_method": method,
+			"fields.grpc_code":   code.String(),
+			"fields.peer":        "bufconn",
+			"message":            method + " " + code.String(),
+		}
+		for key, value := range want {
+			if c.fields[key] != value {
+				t.Errorf("got %s %s %#v, want %#v", kind, key, c.fields[key], value)
+			}
+		}
+		if latency, ok := c.fields["fields.latency"].(float64); !ok || latency < 0 {
+			t.Errorf("got %s latency %#v", kind, c.fields["fields.latency"])
+		}
+		if _, ok := c.fields["fields.error"]; ok != (code != codes.OK) {
+			t.Errorf("got %s error %#v for %s", kind, c.fields["fields.error"], code)
+		}
+	}
+}
+
+func TestUnaryInterceptors(t *testing.T) {
+	tests := []struct {
+		name     string
+		service  string
+		code     codes.Code
+		severity string
+	}{
+		{"ok", "", codes.OK, "info"},
+		{"caller error", "missing", codes.NotFound, "warn"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, backend := newHealthClient(t, health.NewServer())
+			_, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: tt.service})
+			if got := status.Code(err); got != tt.code {
+				t.Fatalf("got code %s, want %s", got, tt.code)
+			}
+			checkCalls(t, backend.waitCalls(t, 2), "unary", "/grpc.health.v1.Health/Check", tt.code, tt.severity)
+		})
+	}
+}
+
+func TestStreamInterceptors(t *testing.T) {
+	client, backend := newHealthClient(t, health.NewServer())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream, err := client.Watch(ctx, &healthpb.HealthCheckRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stream.Recv(); err != nil {
+		t.Fatal(err)
+	}
+	// a streaming call is only logged once it ends
+	if calls := backend.calls(t); len(calls) != 0 {
+		t.Fatalf("got calls %v logged before the stream ended", calls)
+	}
+	cancel()
+	if _, err := stream.Recv(); status.Code(err) != codes.Canceled {
+		t.Fatalf("got error %v, want the call canceled", err)
+	}
+	checkCalls(t, backend.waitCalls(t, 2), "stream", "/grpc.health.v1.Health/Watch", codes.Canceled, "warn")
+}
+
+func TestCodeLevel(t *testing.T) {
+	tests := []struct {
+		code codes.Code
+		want logging.Level
+	}{
+		{codes.OK, logging.InfoLevel},
+		{codes.NotFound, logging.WarnLevel},
+		{codes.Unauthenticated, logging.WarnLevel},
+		{codes.Internal, logging.ErrorLevel},
+		{codes.Unavailable, logging.ErrorLevel},
+		{codes.Unknown, logging.ErrorLevel},
+	}
+	for _, tt := range tests {
+		if got := CodeLevel(tt.code); got != tt.want {
+			t.Errorf("got level %v for %s, want %v", got, tt.code, tt.want)
+		}
+	}
+}
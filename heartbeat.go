@@ -0,0 +1,78 @@
+package influxlogger
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/influxdata/line-protocol/v2/lineprotocol"
+)
+
+// DefaultHeartbeatMeasurement is the measurement heartbeat points are
+// written to.
+const DefaultHeartbeatMeasurement = "heartbeat"
+
+// heartbeat writes a point every interval while the writer is open.
+type heartbeat struct {
+	interval time.Duration
+	// started is when the writer started, which uptime is counted from
+	started time.Time
+	pid     int64
+	stopped chan struct{}
+}
+
+// runHeartbeat writes a heartbeat right away and then every interval until
+// the writer is closed.
+func (w *LogWriter) runHeartbeat() {
+	defer close(w.heartbeat.stopped)
+	ticker := time.NewTicker(w.heartbeat.interval)
+	defer ticker.Stop()
+	for {
+		if err := w.writeHeartbeat(w.ctx); err != nil && w.ctx.Err() == nil {
+			w.handleError(err)
+		}
+		select {
+		case <-ticker.C:
+		case <-w.done:
+			return
+		}
+	}
+}
+
+// writeHeartbeat writes one heartbeat point, tagged with the tags shared by
+// every entry of the writer. Nothing is written while the server has paused
+// writes.
+func (w *LogWriter) writeHeartbeat(ctx context.Context) (err error) {
+	defer w.recoverPanic(&err)
+	if w.paused() {
+		return nil
+	}
+	h := w.heartbeat
+	now := time.Now()
+	enc := &lineprotocol.Encoder{}
+	enc.StartLine(DefaultHeartbeatMeasurement)
+	for _, t := range sortedTags(w.defaultTags) {
+		enc.AddTag(t.key, t.value)
+	}
+	enc.AddField("pid", lineprotocol.IntValue(h.pid))
+	enc.AddField("uptime", lineprotocol.IntValue(int64(now.Sub(h.started)/time.Second)))
+	enc.EndLine(now)
+	if err := enc.Err(); err != nil {
+		return err
+	}
+	err = w.backend.WriteLineProtocol(ctx, enc.Bytes())
+	if retryAfter, ok := rateLimited(err); ok {
+		w.pause(retryAfter)
+	}
+	return err
+}
+
+// newHeartbeat returns a heartbeat of the current process. Its uptime is
+// counted from when the writer starts it.
+func newHeartbeat(interval time.Duration) *heartbeat {
+	return &heartbeat{
+		interval: interval,
+		pid:      int64(os.Getpid()),
+		stopped:  make(chan struct{}),
+	}
+}
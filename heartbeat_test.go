@@ -0,0 +1,111 @@
+package influxlogger
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestHeartbeat(t *testing.T) {
+	const interval = 20 * time.Millisecond
+	backend := &recordingBackend{}
+	writer := newTestWriter(t, "", 0, WithBackend(backend), WithHeartbeat(interval))
+	// a heartbeat is written right away, then every interval
+	deadline := time.Now().Add(5 * time.Second)
+	var lines []line
+	for len(lines) < 3 && time.Now().Before(deadline) {
+		time.Sleep(interval)
+		lines = backend.written(t)
+	}
+	if len(lines) < 3 {
+		t.Fatalf("got %d heartbeats, want at least 3", len(lines))
+	}
+	for _, l := range lines {
+		if l.measurement != DefaultHeartbeatMeasurement {
+			t.Errorf("got measurement %q, want %q", l.measurement, DefaultHeartbeatMeasurement)
+		}
+		if l.tags["appname"] != "test" || l.tags["host"] != "localhost" {
+			t.Errorf("got tags %v, want the appname and host", l.tags)
+		}
+		if l.fields["pid"] != int64(os.Getpid()) {
+			t.Errorf("got pid %#v, want %d", l.fields["pid"], os.Getpid())
+		}
+		if uptime, ok := l.fields["uptime"].(int64); !ok || uptime < 0 {
+			t.Errorf("got uptime %#v", l.fields["uptime"])
+		}
+	}
+	// closing the writer stops heartbeats
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	n := len(backend.written(t))
+	time.Sleep(3 * interval)
+	if got := len(backend.written(t)); got != n {
+		t.Errorf("got %d heartbeats after closing, want %d", got, n)
+	}
+	if err := optionsError(WithHeartbeat(0)); err == nil {
+		t.Error("expected a zero heartbeat interval to be rejected")
+	}
+}
+
+func TestWriteHeartbeat(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		paused   bool
+		requests int
+		wantErr  bool
+		// pausing is set when the heartbeat should pause writes
+		pausing bool
+	}{
+		{"written", http.StatusNoContent, false, 1, false, false},
+		{"rate limited", http.StatusTooManyRequests, false, 1, true, true},
+		{"paused", http.StatusNoContent, true, 0, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// the first heartbeat is written as the writer opens
+			server := newTestServer(t, func(rw http.ResponseWriter, r request, n int) {
+				if n == 1 {
+					rw.WriteHeader(http.StatusNoContent)
+					return
+				}
+				if tt.status == http.StatusTooManyRequests {
+					rw.Header().Set("Retry-After", "3600")
+				}
+				rw.WriteHeader(tt.status)
+			})
+			writer := newTestWriter(t, "lp+"+server.URL+"/write", 0, WithHeartbeat(time.Hour))
+			deadline := time.Now().Add(5 * time.Second)
+			for len(server.received()) == 0 && time.Now().Before(deadline) {
+				time.Sleep(time.Millisecond)
+			}
+			if len(server.received()) == 0 {
+				t.Fatal("no heartbeat written as the writer opened")
+			}
+			if tt.paused {
+				writer.pause(time.Hour)
+			}
+			err := writer.writeHeartbeat(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Errorf("got error %v, want one: %t", err, tt.wantErr)
+			}
+			if writer.paused() != tt.pausing {
+				t.Errorf("got writes paused: %t, want %t", writer.paused(), tt.pausing)
+			}
+			requests := server.received()[1:]
+			if len(requests) != tt.requests {
+				t.Fatalf("got %d requests, want %d", len(requests), tt.requests)
+			}
+			if tt.requests == 0 || tt.wantErr {
+				return
+			}
+			lines := decodeLines(t, requests[0].body)
+			if len(lines) != 1 || lines[0].measurement != DefaultHeartbeatMeasurement {
+				t.Errorf("got lines %v, want one heartbeat", lines)
+			}
+		})
+	}
+}
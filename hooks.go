@@ -0,0 +1,123 @@
+package influxlogger
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hadi77ir/go-logging"
+)
+
+// levelHook is a callback registered through OnLevel.
+type levelHook struct {
+	level logging.Level
+	fn    func(Entry)
+}
+
+// hookCall is a hook invocation waiting on the async hook queue.
+type hookCall struct {
+	fn    func(Entry)
+	entry Entry
+}
+
+var errHookQueueFull = errors.New("hook queue is full, dropping hook call")
+
+// hookDrainTimeout bounds how long logging at the fatal level waits for
+// queued hooks to run before exiting.
+const hookDrainTimeout = 5 * time.Second
+
+// OnLevel registers hook to be called with every entry logged at level or
+// above, after it passes the level filter and before it is buffered or
+// written, so the hook sees entries even when the write fails. Hooks run
+// synchronously on the logging goroutine unless WithAsyncHooks is set. The
+// entry's Fields map is shared with the writer and must not be modified.
+func (w *LogWriter) OnLevel(level logging.Level, hook func(Entry)) {
+	if hook == nil {
+		return
+	}
+	w.hooksMutex.Lock()
+	defer w.hooksMutex.Unlock()
+	// copy on write, so fireHooks reads the list without locking
+	var hooks []levelHook
+	if current := w.hooks.Load(); current != nil {
+		hooks = append(hooks, *current...)
+	}
+	hooks = append(hooks, levelHook{level: level, fn: hook})
+	w.hooks.Store(&hooks)
+}
+
+func (w *LogWriter) fireHooks(e *Entry) {
+	hooks := w.hooks.Load()
+	if hooks == nil {
+		return
+	}
+	for _, h := range *hooks {
+		if levelRank[e.Level] < levelRank[h.level] {
+			continue
+		}
+		if w.hookQueue == nil {
+			w.callHook(h.fn, *e)
+			continue
+		}
+		select {
+		case w.hookQueue <- hookCall{fn: h.fn, entry: *e}:
+		default:
+			w.handleError(errHookQueueFull)
+		}
+	}
+}
+
+func (w *LogWriter) callHook(fn func(Entry), e Entry) {
+	defer func() {
+		if r := recover(); r != nil {
+			w.handleError(fmt.Errorf("recovered from panic in level hook: %v", r))
+		}
+	}()
+	fn(e)
+}
+
+// runHooks calls queued hooks until the writer is closed, then drains the
+// queue.
+func (w *LogWriter) runHooks() {
+	defer close(w.hooksStopped)
+	for {
+		select {
+		case call := <-w.hookQueue:
+			w.callHook(call.fn, call.entry)
+		case <-w.done:
+			for {
+				select {
+				case call := <-w.hookQueue:
+					w.callHook(call.fn, call.entry)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// drainHooks waits until the hook calls queued so far have run, or until
+// timeout passes. It returns right away if hooks run synchronously.
+func (w *LogWriter) drainHooks(timeout time.Duration) {
+	if w.hookQueue == nil {
+		return
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	// the queue is run in order, so every earlier call has run once the
+	// marker has
+	drained := make(chan struct{})
+	select {
+	case w.hookQueue <- hookCall{fn: func(Entry) { close(drained) }}:
+	case <-w.hooksStopped:
+		return
+	case <-timer.C:
+		return
+	}
+	select {
+	case <-drained:
+	case <-w.hooksStopped:
+	case <-timer.C:
+	}
+}
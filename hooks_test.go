@@ -0,0 +1,93 @@
+package influxlogger
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hadi77ir/go-logging"
+)
+
+func TestOnLevel(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+	}{
+		{"sync", nil},
+		{"async", []Option{WithAsyncHooks(10)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mutex sync.Mutex
+			var fired []string
+			writer := newTestWriter(t, "", 0, append(tt.opts, WithBackend(&recordingBackend{}))...)
+			writer.OnLevel(logging.ErrorLevel, func(e Entry) {
+				mutex.Lock()
+				defer mutex.Unlock()
+				fired = append(fired, e.Message)
+			})
+			levels := map[string]logging.Level{
+				"info":  logging.InfoLevel,
+				"warn":  logging.WarnLevel,
+				"error": logging.ErrorLevel,
+				"fatal": logging.FatalLevel,
+			}
+			for _, message := range []string{"info", "warn", "error", "fatal"} {
+				if err := writer.Write(levels[message], []any{message}, nil); err != nil {
+					t.Fatal(err)
+				}
+			}
+			writer.drainHooks(time.Second)
+			mutex.Lock()
+			defer mutex.Unlock()
+			if want := []string{"error", "fatal"}; !slices.Equal(fired, want) {
+				t.Errorf("got hooks fired for %q, want %q", fired, want)
+			}
+		})
+	}
+}
+
+// fatalHooksEnv makes the test binary log fatal entries through a writer
+// with a slow async hook instead of running the tests, appending a line to
+// the file it names every time the hook fires.
+const fatalHooksEnv = "INFLUXLOGGER_FATAL_HOOKS"
+
+func TestFatalDrainsHooks(t *testing.T) {
+	if path := os.Getenv(fatalHooksEnv); path != "" {
+		logger, err := NewLogger("", "test", "localhost", "1", WithBackend(&recordingBackend{}), WithAsyncHooks(10))
+		if err != nil {
+			t.Fatal(err)
+		}
+		logger.(*Logger).writer.OnLevel(logging.ErrorLevel, func(e Entry) {
+			time.Sleep(50 * time.Millisecond)
+			f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+			if err != nil {
+				panic(err)
+			}
+			defer f.Close()
+			_, _ = f.WriteString(e.Message + "\n")
+		})
+		logger.Log(logging.ErrorLevel, "error")
+		logger.Log(logging.FatalLevel, "fatal")
+		t.Fatal("expected logging at the fatal level to exit")
+	}
+	path := filepath.Join(t.TempDir(), "hooks")
+	cmd := exec.Command(os.Args[0], "-test.run=^TestFatalDrainsHooks$")
+	cmd.Env = append(os.Environ(), fatalHooksEnv+"="+path)
+	err := cmd.Run()
+	if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 1 {
+		t.Fatalf("got %v, want exit status 1", err)
+	}
+	fired, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Fields(string(fired)); !slices.Equal(got, []string{"error", "fatal"}) {
+		t.Errorf("got hooks fired for %q, want every entry once", got)
+	}
+}
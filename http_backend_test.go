@@ -0,0 +1,182 @@
+package influxlogger
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hadi77ir/go-logging"
+)
+
+func TestInfluxDB1Backend(t *testing.T) {
+	tests := []struct {
+		name string
+		// connection is appended to influxdb1+http://
+		connection    string
+		path          string
+		rp            string
+		authorization string
+	}{
+		{"anonymous", "HOST?db=logs", "/write", "", ""},
+		{"query credentials", "HOST?db=logs&u=alice&p=secret", "/write", "", "alice:secret"},
+		{"url credentials", "alice:secret@HOST?db=logs&u=bob&p=other", "/write", "", "alice:secret"},
+		{"retention policy", "HOST?db=logs&rp=week", "/write", "week", ""},
+		{"base path", "HOST/influx/?db=logs", "/influx/write", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTestServer(t, func(rw http.ResponseWriter, r request, n int) {
+				rw.WriteHeader(http.StatusNoContent)
+			})
+			host := strings.TrimPrefix(server.URL, "http://")
+			writer := newTestWriter(t, "influxdb1+http://"+strings.Replace(tt.connection, "HOST", host, 1), 0)
+			writeMessages(t, writer, "hello")
+			requests := server.received()
+			if len(requests) != 1 {
+				t.Fatalf("got %d requests, want 1", len(requests))
+			}
+			r := requests[0]
+			if r.method != http.MethodPost || r.path != tt.path {
+				t.Errorf("got %s %s, want POST %s", r.method, r.path, tt.path)
+			}
+			if db, precision := r.query.Get("db"), r.query.Get("precision"); db != "logs" || precision != "ns" {
+				t.Errorf("got db %q and precision %q", db, precision)
+			}
+			if rp := r.query.Get("rp"); rp != tt.rp {
+				t.Errorf("got rp %q, want %q", rp, tt.rp)
+			}
+			var authorization string
+			if tt.authorization != "" {
+				authorization = "Basic " + base64.StdEncoding.EncodeToString([]byte(tt.authorization))
+			}
+			if got := r.header.Get("Authorization"); got != authorization {
+				t.Errorf("got Authorization %q, want %q", got, authorization)
+			}
+			if got := messages(t, r.body); !slices.Equal(got, []string{"hello"}) {
+				t.Errorf("wrote %q", got)
+			}
+		})
+	}
+}
+
+func TestInfluxDB1BackendErrors(t *testing.T) {
+	if _, err := NewLogWriter("influxdb1+http://localhost:8086", "test", "localhost", "1", 0, 0); err == nil {
+		t.Error("expected a connection string without a database to be rejected")
+	}
+	server := newTestServer(t, func(rw http.ResponseWriter, r request, n int) {
+		rw.WriteHeader(http.StatusNotFound)
+		_, _ = io.WriteString(rw, `{"error":"database not found: \"logs\""}`)
+	})
+	writer := newTestWriter(t, "influxdb1+"+server.URL+"?db=logs", 0)
+	err := writer.Write(logging.InfoLevel, []any{"hello"}, nil)
+	var writeErr *WriteError
+	if !errors.As(err, &writeErr) {
+		t.Fatalf("got %v, want a *WriteError", err)
+	}
+	if writeErr.StatusCode != http.StatusNotFound || !strings.Contains(string(writeErr.Body), "database not found") {
+		t.Errorf("got status %d and body %q", writeErr.StatusCode, writeErr.Body)
+	}
+}
+
+func TestNewHTTPBackend(t *testing.T) {
+	server := newTestServer(t, func(rw http.ResponseWriter, r request, n int) {
+		rw.WriteHeader(http.StatusNoContent)
+	})
+	backend, err := NewHTTPBackend(HTTPBackendConfig{
+		URL:           server.URL + "/api/?db=logs",
+		Path:          "/write",
+		Authorization: "Bearer secret",
+		ContentType:   "application/x-influx",
+		Header:        http.Header{"X-Scope-Orgid": {"tenant"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	writer := newTestWriter(t, "", 0, WithBackend(backend))
+	writeMessages(t, writer, "hello")
+	requests := server.received()
+	if len(requests) != 1 {
+		t.Fatalf("got %d requests, want 1", len(requests))
+	}
+	r := requests[0]
+	if r.path != "/api/write" || r.query.Get("db") != "logs" {
+		t.Errorf("got %s?%s", r.path, r.query.Encode())
+	}
+	for key, want := range map[string]string{
+		"Authorization": "Bearer secret",
+		"Content-Type":  "application/x-influx",
+		"X-Scope-Orgid": "tenant",
+	} {
+		if got := r.header.Get(key); got != want {
+			t.Errorf("got %s %q, want %q", key, got, want)
+		}
+	}
+	if got := messages(t, r.body); !slices.Equal(got, []string{"hello"}) {
+		t.Errorf("wrote %q", got)
+	}
+}
+
+func TestNewHTTPBackendTransport(t *testing.T) {
+	t.Run("tls", func(t *testing.T) {
+		server := httptest.NewTLSServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			rw.WriteHeader(http.StatusNoContent)
+		}))
+		defer server.Close()
+		roots := x509.NewCertPool()
+		roots.AddCert(server.Certificate())
+		for _, trusted := range []bool{false, true} {
+			config := HTTPBackendConfig{URL: server.URL}
+			if trusted {
+				config.TLSConfig = &tls.Config{RootCAs: roots}
+			}
+			backend, err := NewHTTPBackend(config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = backend.WriteLineProtocol(context.Background(), []byte("logs message=\"hello\"\n"))
+			if trusted && err != nil {
+				t.Errorf("got %v with the server's CA trusted", err)
+			}
+			if !trusted && err == nil {
+				t.Error("expected the server's certificate to be rejected")
+			}
+			_ = backend.Close()
+		}
+	})
+	t.Run("proxy", func(t *testing.T) {
+		proxy := newTestServer(t, func(rw http.ResponseWriter, r request, n int) {
+			rw.WriteHeader(http.StatusNoContent)
+		})
+		backend, err := NewHTTPBackend(HTTPBackendConfig{URL: "http://influxdb.invalid/write", Proxy: proxy.URL})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer backend.Close()
+		if err := backend.WriteLineProtocol(context.Background(), []byte("logs message=\"hello\"\n")); err != nil {
+			t.Fatal(err)
+		}
+		if requests := proxy.received(); len(requests) != 1 || requests[0].path != "/write" {
+			t.Errorf("got %+v, want the write to go through the proxy", requests)
+		}
+	})
+	t.Run("invalid", func(t *testing.T) {
+		for _, config := range []HTTPBackendConfig{
+			{URL: "ftp://localhost/write"},
+			{URL: "http://localhost/write", Proxy: "localhost:3128"},
+			{URL: "http://localhost/write", Transport: TransportConfig{DialTimeout: -time.Second}},
+		} {
+			if _, err := NewHTTPBackend(config); err == nil {
+				t.Errorf("expected %+v to be rejected", config)
+			}
+		}
+	})
+}
@@ -0,0 +1,129 @@
+package influxlogger
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/hadi77ir/go-logging"
+)
+
+// HTTPRequest describes a served HTTP request, as logged by
+// LogHTTPRequest.
+type HTTPRequest struct {
+	Method string
+	// Path is the path of the request URL
+	Path string
+	// Route is the pattern the request was routed by, if known
+	Route      string
+	RemoteAddr string
+	UserAgent  string
+	// Status is the status code of the response
+	Status int
+	// Size is the number of bytes of the response body
+	Size    int64
+	Latency time.Duration
+}
+
+// HTTPStatusLevel returns the level a response with status is logged at:
+// server errors at error, client errors at warning and others at info.
+func HTTPStatusLevel(status int) logging.Level {
+	switch {
+	case status >= http.StatusInternalServerError:
+		return logging.ErrorLevel
+	case status >= http.StatusBadRequest:
+		return logging.WarnLevel
+	default:
+		return logging.InfoLevel
+	}
+}
+
+// LogHTTPRequest writes an access log entry for r through w. It is the
+// common ground of HTTPMiddleware and the framework adapters.
+func LogHTTPRequest(w *LogWriter, r HTTPRequest) {
+	level := HTTPStatusLevel(r.Status)
+	if !w.Enabled(level) {
+		return
+	}
+	fields := logging.Fields{
+		"http_method": r.Method,
+		"http_path":   r.Path,
+		"http_status": r.Status,
+		"http_size":   r.Size,
+		"remote_addr": r.RemoteAddr,
+		"user_agent":  r.UserAgent,
+		"latency":     r.Latency,
+	}
+	if r.Route != "" {
+		fields["http_route"] = r.Route
+	}
+	_ = w.Write(level, []any{r.Method, " ", r.Path, " ", r.Status}, fields)
+}
+
+// HTTPMiddleware returns middleware writing an access log entry through w
+// for every request served by the handler it wraps.
+func HTTPMiddleware(w *LogWriter) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			start := time.Now()
+			recorder := &responseRecorder{ResponseWriter: rw, status: http.StatusOK}
+			defer func() {
+				LogHTTPRequest(w, HTTPRequest{
+					Method:     req.Method,
+					Path:       req.URL.Path,
+					Route:      req.Pattern,
+					RemoteAddr: req.RemoteAddr,
+					UserAgent:  req.UserAgent(),
+					Status:     recorder.status,
+					Size:       recorder.size,
+					Latency:    time.Since(start),
+				})
+			}()
+			next.ServeHTTP(recorder, req)
+		})
+	}
+}
+
+// responseRecorder records the status and body size of a response.
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	size        int64
+	wroteHeader bool
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	n, err := r.ResponseWriter.Write(b)
+	r.size += int64(n)
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the wrapped writer.
+func (r *responseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+func (r *responseRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (r *responseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := r.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response writer doesn't support hijacking")
+	}
+	return h.Hijack()
+}
@@ -0,0 +1,90 @@
+package influxlogger
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	influxdb2 "github.com/influxdata/influxdb-client-go/v2"
+	"github.com/influxdata/influxdb-client-go/v2/api"
+	http2 "github.com/influxdata/influxdb-client-go/v2/api/http"
+)
+
+// influxDB2Backend writes through influxdb-client-go, the client of
+// InfluxDB 2.x. Only its blocking write API is used, so batching and
+// retries are left to the writer.
+type influxDB2Backend struct {
+	client     influxdb2.Client
+	httpClient *http.Client
+	write      api.WriteAPIBlocking
+	admin      *influxDB2Admin
+}
+
+// newInfluxDB2Backend creates a backend for InfluxDB 2.x from a connection
+// string like
+//
+//	influxdb2+https://localhost:8086?org=acme&bucket=logs&token=secret
+func newInfluxDB2Backend(connection string, options *transportOptions) (*influxDB2Backend, error) {
+	u, err := url.Parse(connection)
+	if err != nil {
+		return nil, err
+	}
+	values := u.Query()
+	org, bucket := values.Get("org"), values.Get("bucket")
+	if org == "" || bucket == "" {
+		return nil, errors.New("missing org or bucket")
+	}
+	token := values.Get("token")
+	server := url.URL{
+		Scheme: strings.TrimPrefix(u.Scheme, "influxdb2+"),
+		Host:   u.Host,
+		Path:   strings.TrimSuffix(u.Path, "/"),
+	}
+	httpClient := &http.Client{
+		Timeout:   defaultHTTPTimeout,
+		Transport: options.roundTripper(options.newTransport()),
+	}
+	client := influxdb2.NewClientWithOptions(server.String(), token, influxdb2.DefaultOptions().
+		SetHTTPClient(httpClient).
+		SetApplicationName("go-influxlogger"))
+	adminHeader := http.Header{}
+	if token != "" {
+		adminHeader.Set("Authorization", "Token "+token)
+	}
+	return &influxDB2Backend{
+		client:     client,
+		httpClient: httpClient,
+		write:      client.WriteAPIBlocking(org, bucket),
+		admin: &influxDB2Admin{
+			adminClient: adminClient{client: httpClient, base: server, header: adminHeader},
+			org:         org,
+			bucket:      bucket,
+		},
+	}, nil
+}
+
+func (b *influxDB2Backend) WriteLineProtocol(ctx context.Context, lines []byte) error {
+	err := b.write.WriteRecord(ctx, strings.TrimSuffix(string(lines), "\n"))
+	var httpErr *http2.Error
+	if !errors.As(err, &httpErr) || httpErr.StatusCode == 0 {
+		return err
+	}
+	retryAfter, retryAfterSent := parseRetryAfter(httpErr.Header.Get("Retry-After"), time.Now())
+	return &WriteError{
+		StatusCode:     httpErr.StatusCode,
+		RetryAfter:     retryAfter,
+		RetryAfterSent: retryAfterSent,
+		Body:           []byte(httpErr.Message),
+		Err:            err,
+	}
+}
+
+func (b *influxDB2Backend) Close() error {
+	b.client.Close()
+	// the client leaves connections of an HTTP client it was given open
+	b.httpClient.CloseIdleConnections()
+	return nil
+}
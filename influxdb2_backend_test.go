@@ -0,0 +1,96 @@
+package influxlogger
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/hadi77ir/go-logging"
+)
+
+func TestInfluxDB2Backend(t *testing.T) {
+	server := newTestServer(t, func(rw http.ResponseWriter, r request, n int) {
+		rw.WriteHeader(http.StatusNoContent)
+	})
+	writer := newTestWriter(t, "influxdb2+"+server.URL+"?org=acme&bucket=logs&token=secret", 0)
+	writeMessages(t, writer, "hello")
+	requests := server.received()
+	if len(requests) != 1 {
+		t.Fatalf("got %d requests, want 1", len(requests))
+	}
+	r := requests[0]
+	if r.method != http.MethodPost || r.path != "/api/v2/write" {
+		t.Errorf("got %s %s, want POST /api/v2/write", r.method, r.path)
+	}
+	for key, want := range map[string]string{"org": "acme", "bucket": "logs", "precision": "ns"} {
+		if got := r.query.Get(key); got != want {
+			t.Errorf("got %s %q, want %q", key, got, want)
+		}
+	}
+	if got := r.header.Get("Authorization"); got != "Token secret" {
+		t.Errorf("got Authorization %q", got)
+	}
+	if got := messages(t, r.body); !slices.Equal(got, []string{"hello"}) {
+		t.Errorf("wrote %q", got)
+	}
+}
+
+func TestInfluxDB2BackendErrors(t *testing.T) {
+	for _, connection := range []string{"influxdb2+http://localhost:8086?org=acme", "influxdb2+http://localhost:8086?bucket=logs"} {
+		if _, err := NewLogWriter(connection, "test", "localhost", "1", 0, 0); err == nil {
+			t.Errorf("expected %s to be rejected", connection)
+		}
+	}
+	tests := []struct {
+		name       string
+		status     int
+		retryAfter string
+		body       string
+		wantBody   string
+		wantRetry  time.Duration
+		wantSent   bool
+	}{
+		{
+			name:     "rejected",
+			status:   http.StatusBadRequest,
+			body:     `{"code":"invalid","message":"unable to parse line"}`,
+			wantBody: "unable to parse line",
+		},
+		{
+			name:       "rate limited",
+			status:     http.StatusTooManyRequests,
+			retryAfter: "30",
+			body:       `{"code":"too many requests","message":"slow down"}`,
+			wantBody:   "slow down",
+			wantRetry:  30 * time.Second,
+			wantSent:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTestServer(t, func(rw http.ResponseWriter, r request, n int) {
+				rw.Header().Set("Content-Type", "application/json")
+				if tt.retryAfter != "" {
+					rw.Header().Set("Retry-After", tt.retryAfter)
+				}
+				rw.WriteHeader(tt.status)
+				_, _ = io.WriteString(rw, tt.body)
+			})
+			writer := newTestWriter(t, "influxdb2+"+server.URL+"?org=acme&bucket=logs", 0)
+			err := writer.Write(logging.InfoLevel, []any{"hello"}, nil)
+			var writeErr *WriteError
+			if !errors.As(err, &writeErr) {
+				t.Fatalf("got %v, want a *WriteError", err)
+			}
+			if writeErr.StatusCode != tt.status || string(writeErr.Body) != tt.wantBody {
+				t.Errorf("got status %d and body %q", writeErr.StatusCode, writeErr.Body)
+			}
+			if writeErr.RetryAfter != tt.wantRetry || writeErr.RetryAfterSent != tt.wantSent {
+				t.Errorf("got Retry-After %v, %v", writeErr.RetryAfter, writeErr.RetryAfterSent)
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package influxlogger
+
+import "sync"
+
+// maxInterned bounds the number of strings an interner keeps, so that keys
+// of unbounded cardinality don't grow it forever.
+const maxInterned = 4096
+
+// interner caches the strings derived from keys, such as prefixed field
+// names, so repeated keys don't allocate a new string for every entry.
+type interner struct {
+	derive  func(key string) string
+	mutex   sync.RWMutex
+	strings map[string]string
+}
+
+func newInterner(derive func(key string) string) *interner {
+	return &interner{derive: derive, strings: map[string]string{}}
+}
+
+// get returns the string derived from key. Once the interner is full, keys
+// it doesn't hold are derived without taking the write lock.
+func (in *interner) get(key string) string {
+	in.mutex.RLock()
+	s, ok := in.strings[key]
+	full := len(in.strings) >= maxInterned
+	in.mutex.RUnlock()
+	if ok {
+		return s
+	}
+	s = in.derive(key)
+	if full {
+		return s
+	}
+	in.mutex.Lock()
+	if len(in.strings) < maxInterned {
+		in.strings[key] = s
+	}
+	in.mutex.Unlock()
+	return s
+}
@@ -0,0 +1,32 @@
+package influxlogger
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestInterner(t *testing.T) {
+	derived := 0
+	in := newInterner(func(key string) string {
+		derived++
+		return "fields." + key
+	})
+	for range 2 {
+		if got := in.get("status"); got != "fields.status" {
+			t.Fatalf("got %q, want fields.status", got)
+		}
+	}
+	if derived != 1 {
+		t.Errorf("derived %d times, want once", derived)
+	}
+	for i := len(in.strings); i < maxInterned; i++ {
+		in.get(fmt.Sprint(i))
+	}
+	// a full interner still derives keys, without keeping them
+	if got := in.get("path"); got != "fields.path" {
+		t.Errorf("got %q, want fields.path", got)
+	}
+	if _, ok := in.strings["path"]; ok || len(in.strings) != maxInterned {
+		t.Errorf("got %d interned strings, want %d without path", len(in.strings), maxInterned)
+	}
+}
@@ -0,0 +1,73 @@
+package influxlogger
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// jsonLine is the JSON form of an entry, holding what the point written
+// for it holds.
+type jsonLine struct {
+	Measurement string            `json:"measurement"`
+	Tags        map[string]string `json:"tags"`
+	Fields      map[string]any    `json:"fields"`
+	Time        string            `json:"time"`
+	// Error is the reason the entry couldn't be written, if any
+	Error string `json:"error,omitempty"`
+}
+
+// JSONLinesEncoder writes entries as JSON objects, one per line, with the
+// measurement, tags, fields and time they are written to InfluxDB with, so
+// that entries kept out of InfluxDB can be parsed by other tools. It is safe
+// for concurrent use.
+type JSONLinesEncoder struct {
+	writer *LogWriter
+	mutex  sync.Mutex
+	enc    *json.Encoder
+}
+
+// JSONLinesEncoder returns an encoder writing entries to out following the
+// schema of w.
+func (w *LogWriter) JSONLinesEncoder(out io.Writer) *JSONLinesEncoder {
+	return &JSONLinesEncoder{writer: w, enc: json.NewEncoder(out)}
+}
+
+// Encode writes e as a single line. A non-nil reason is stored as the error
+// of the line.
+func (j *JSONLinesEncoder) Encode(e Entry, reason error) error {
+	w := j.writer
+	line := jsonLine{
+		Measurement: w.measurement,
+		Tags:        w.allEntryTags(&e),
+		Fields:      make(map[string]any, len(w.fields)+len(e.Fields)+1),
+		Time:        e.Time.UTC().Format(time.RFC3339Nano),
+	}
+	for key, value := range w.fields {
+		line.Fields[key] = fieldValue(w.entryField(&e, key, value))
+	}
+	err := w.eachEntryField(&e, func(key string, value any) error {
+		line.Fields[key] = fieldValue(value)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if reason != nil {
+		line.Error = reason.Error()
+	}
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	return j.enc.Encode(&line)
+}
+
+// sink returns a dead-letter or fallback sink writing to j, reporting
+// encoding failures to the error handler.
+func (j *JSONLinesEncoder) sink() func(e Entry, reason error) {
+	return func(e Entry, reason error) {
+		if err := j.Encode(e, reason); err != nil {
+			j.writer.handleError(err)
+		}
+	}
+}
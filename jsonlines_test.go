@@ -0,0 +1,86 @@
+package influxlogger
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hadi77ir/go-logging"
+)
+
+func TestJSONLinesEncoder(t *testing.T) {
+	writer := newTestWriter(t, "", 0, WithBackend(&recordingBackend{}))
+	var out bytes.Buffer
+	enc := writer.JSONLinesEncoder(&out)
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	entries := []Entry{
+		{Level: logging.ErrorLevel, Time: at, Message: "failed", Fields: logging.Fields{"attempt": 2}},
+		{Level: logging.InfoLevel, Time: at, Message: "done"},
+	}
+	if err := enc.Encode(entries[0], errors.New("server unreachable")); err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.Encode(entries[1], nil); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2", len(lines))
+	}
+	var first, second jsonLine
+	if err := json.Unmarshal([]byte(lines[0]), &first); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(lines[1]), &second); err != nil {
+		t.Fatal(err)
+	}
+	if first.Measurement != DefaultMeasurement || first.Time != "2024-05-01T12:00:00Z" || first.Error != "server unreachable" {
+		t.Errorf("got %+v", first)
+	}
+	if first.Tags["severity"] != "err" || first.Tags["appname"] != "test" {
+		t.Errorf("got tags %v", first.Tags)
+	}
+	// numbers come back as float64 from JSON
+	if first.Fields["message"] != "failed" || first.Fields["fields.attempt"] != float64(2) || first.Fields["severity_code"] != float64(3) {
+		t.Errorf("got fields %v", first.Fields)
+	}
+	if second.Error != "" || strings.Contains(lines[1], `"error"`) {
+		t.Errorf("got error %q for an entry without a reason", second.Error)
+	}
+}
+
+func TestJSONSinks(t *testing.T) {
+	tests := []struct {
+		name   string
+		option func(out io.Writer) Option
+		status int
+		body   string
+	}{
+		{"fallback", WithFallbackJSON, http.StatusInternalServerError, ""},
+		{"dead letter", WithDeadLetterJSON, http.StatusBadRequest, `{"error":"partial write of line protocol occurred","data":[` +
+			`{"original_line":"","line_number":1,"error_message":"invalid column type"}]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTestServer(t, func(rw http.ResponseWriter, r request, n int) {
+				rw.WriteHeader(tt.status)
+				_, _ = io.WriteString(rw, tt.body)
+			})
+			var out bytes.Buffer
+			writer := newTestWriter(t, "lp+"+server.URL+"/write", 0, tt.option(&out))
+			_ = writer.Write(logging.InfoLevel, []any{"lost"}, nil)
+			var line jsonLine
+			if err := json.Unmarshal(out.Bytes(), &line); err != nil {
+				t.Fatal(err)
+			}
+			if line.Fields["message"] != "lost" || line.Error == "" {
+				t.Errorf("got %+v, want the lost entry with the reason", line)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package influxlogger
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/influxdata/line-protocol/v2/lineprotocol"
+)
+
+type tag struct {
+	key   string
+	value string
+}
+
+// sortedTags returns tags in the lexical key order line protocol expects.
+// Tags with empty values can't be represented and are left out.
+func sortedTags(tags map[string]string) []tag {
+	sorted := make([]tag, 0, len(tags))
+	for key, value := range tags {
+		if value == "" {
+			continue
+		}
+		sorted = append(sorted, tag{key: key, value: value})
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].key < sorted[j].key
+	})
+	return sorted
+}
+
+// encodeEntry appends e to enc as a single line. If the entry can't be
+// encoded, nothing is appended and the encoding error is returned.
+func (w *LogWriter) encodeEntry(enc *lineprotocol.Encoder, e *Entry) error {
+	start := len(enc.Bytes())
+	enc.StartLine(w.measurement)
+	for _, t := range w.entryLineTags(e) {
+		enc.AddTag(t.key, t.value)
+	}
+	for key, value := range w.fields {
+		if err := addField(enc, key, w.entryField(e, key, value)); err != nil {
+			enc.SetBuffer(enc.Bytes()[:start])
+			return err
+		}
+	}
+	err := w.eachEntryField(e, func(key string, value any) error {
+		return addField(enc, key, value)
+	})
+	if err != nil {
+		enc.SetBuffer(enc.Bytes()[:start])
+		return err
+	}
+	enc.EndLine(e.Time)
+	err = enc.Err()
+	enc.ClearErr()
+	return err
+}
+
+func addField(enc *lineprotocol.Encoder, key string, value any) error {
+	v, ok := lineprotocol.NewValue(fieldValue(value))
+	if !ok {
+		return fmt.Errorf("invalid value for field %q", key)
+	}
+	enc.AddField(key, v)
+	return nil
+}
+
+// fieldValue converts value to one of the types line protocol can carry,
+// following the conversions influxdb3.Point applies to its fields.
+func fieldValue(value any) any {
+	switch v := value.(type) {
+	case bool, int64, uint64, float64, string:
+		return v
+	case int:
+		return int64(v)
+	case int8:
+		return int64(v)
+	case int16:
+		return int64(v)
+	case int32:
+		return int64(v)
+	case uint:
+		return uint64(v)
+	case uint8:
+		return uint64(v)
+	case uint16:
+		return uint64(v)
+	case uint32:
+		return uint64(v)
+	case float32:
+		return float64(v)
+	case []byte:
+		return string(v)
+	case time.Duration:
+		return v.Nanoseconds()
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
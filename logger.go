@@ -4,15 +4,24 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"maps"
 	"os"
 	"sync"
+	"sync/atomic"
 	"time"
+	"unicode/utf8"
 
 	"github.com/InfluxCommunity/influxdb3-go/v2/influxdb3"
+	"github.com/InfluxCommunity/influxdb3-go/v2/influxdb3/batching"
 	"github.com/hadi77ir/go-logging"
 	"github.com/hadi77ir/go-ringqueue"
+	"github.com/influxdata/line-protocol/v2/lineprotocol"
 )
 
+// DefaultMeasurement is the measurement entries are written to, matching the
+// one used by Telegraf's syslog input.
+const DefaultMeasurement = "syslog"
+
 var severityMap = map[logging.Level]string{
 	logging.TraceLevel: "debug",
 	logging.DebugLevel: "debug",
@@ -33,36 +42,188 @@ var severityCode = map[logging.Level]int{
 	logging.PanicLevel: 0,
 }
 
+// Entry is a single log record, as written to InfluxDB and handed to
+// callbacks. Callbacks must not modify it.
+type Entry struct {
+	Level   logging.Level
+	Time    time.Time
+	Message string
+	// Template holds the template the message was rendered from, if any.
+	Template string
+	// Fields holds the fields of the logger that produced the entry.
+	Fields logging.Fields
+	// multiline is set for multi-line messages to be tagged as such
+	multiline bool
+	// chunk is set for the parts of a message split under OversizeChunk
+	chunk *chunk
+}
+
+// levelNames holds the go-logging names of the levels.
+var levelNames = map[logging.Level]string{
+	logging.TraceLevel: "trace",
+	logging.DebugLevel: "debug",
+	logging.InfoLevel:  "info",
+	logging.WarnLevel:  "warning",
+	logging.ErrorLevel: "error",
+	logging.FatalLevel: "fatal",
+	logging.PanicLevel: "panic",
+}
+
+// levelRank orders levels from the least to the most severe.
+var levelRank = map[logging.Level]int{
+	logging.TraceLevel: 0,
+	logging.DebugLevel: 1,
+	logging.InfoLevel:  2,
+	logging.WarnLevel:  3,
+	logging.ErrorLevel: 4,
+	logging.FatalLevel: 5,
+	logging.PanicLevel: 6,
+}
+
+// ErrClosed is returned when writing to a LogWriter that has been closed.
+var ErrClosed = errors.New("log writer closed")
+
 type LogWriter struct {
+	// client is set when writing through the influxdb3 client
 	client        *influxdb3.Client
+	backend       Backend
 	measurement   string
 	appName       string
 	host          string
 	tags          map[logging.Level]map[string]string
+	lineTags      map[logging.Level][]tag
 	fields        map[string]any
 	flushInterval time.Duration
-	buffer        ringqueue.RingQueue[*influxdb3.Point]
+	buffer        ringqueue.RingQueue[*Entry]
+	batch         []*Entry
 	flushMutex    sync.Mutex
+	lineProtocol  bool
+	encoders      sync.Pool
+	minLevel      logging.Level
+	maxBatchSize  int
+	// flushConcurrency bounds the chunk writes in flight during a flush
+	flushConcurrency int
+	idleFlush        time.Duration
+	flushJitter      time.Duration
+	writeTimeout     time.Duration
+	activity         chan struct{}
+	errorHandler     func(error)
+	ctx              context.Context
+	cancel           context.CancelFunc
+	done             chan struct{}
+	stopped          chan struct{}
+	closeOnce        sync.Once
+	pausedUntil      atomic.Int64
+	requeue          []*Entry
+	requeueMutex     sync.Mutex
+	deadLetter       func(e Entry, reason error)
+	fallback         func(e Entry, reason error)
+	console          *console
+	transport        transportOptions
+	timestampFormat  TimestampFormat
+	// sdID is the structured data ID of entry fields in RFC 5424 mode
+	sdID          string
+	severityCodes map[logging.Level]int
+	levelField    string
+	multiline     MultilinePolicy
+	// multilineSeparator replaces line breaks under MultilineReplace
+	multilineSeparator string
+	// multilineTags and multilineLineTags hold the tags of multi-line
+	// entries under MultilineTag
+	multilineTags     map[logging.Level]map[string]string
+	multilineLineTags map[logging.Level][]tag
+	bytesEncoding     BytesEncoding
+	maxBytes          int
+	timeFieldFormat   TimestampFormat
+	durationEncoding  DurationEncoding
+	fieldTypeNames    bool
+	invalidValues     InvalidValuePolicy
+	hooks             atomic.Pointer[[]levelHook]
+	hooksMutex        sync.Mutex
+	// hookQueue is set when hooks run asynchronously
+	hookQueue    chan hookCall
+	hooksStopped chan struct{}
+	errorAlert   *errorRateAlert
+	levelMetrics *levelMetrics
+	// batcher replaces buffer when buffering is left to the client's
+	// batching package
+	clientBatching bool
+	batcher        *batching.Batcher
+	// batched counts the points held by batcher
+	batched int
+	// urgentFlush is set when entries at urgentLevel or above are flushed
+	// within urgentInterval instead of waiting for the periodic flush
+	urgentFlush    chan struct{}
+	urgentLevel    logging.Level
+	urgentInterval time.Duration
+	// bufferLen mirrors the number of buffered entries, so it can be read
+	// without waiting for a flush to release flushMutex
+	bufferLen atomic.Int64
+	bufferCap int
+	drops     dropCounters
+	// lastError and lastSuccess hold the outcome of writes, for health
+	// checks
+	lastMutex   sync.Mutex
+	lastError   error
+	lastSuccess time.Time
+	// watermarks is set when crossing the high and low buffer watermarks
+	// is notified to watermarkNotify
+	watermarks      chan watermarkEvent
+	watermarkHigh   int
+	watermarkLow    int
+	watermarkNotify func(over bool, length int)
+	overHigh        bool
+	// defaultTags are the tags shared by every entry, which points leave to
+	// the client's default tags, keeping only the rest in pointTags and
+	// multilinePointTags
+	defaultTags        map[string]string
+	pointTags          map[logging.Level]map[string]string
+	multilinePointTags map[logging.Level]map[string]string
+	// fieldKeys and sdKeys intern the names entry fields are written as
+	fieldKeys *interner
+	sdKeys    *interner
+	// enrichers run for every entry, while the fields of cached enrichers
+	// are computed once, into cachedFields
+	enrichers       []Enricher
+	cachedEnrichers []Enricher
+	cachedFields    logging.Fields
+	// utf8Replacement replaces invalid UTF-8 in messages, fields and tags
+	utf8Replacement string
+	// entryDeadline bounds the time spent writing an unbuffered entry
+	entryDeadline time.Duration
+	// maxMessage is the longest message written as is under oversize
+	maxMessage int
+	oversize   OversizePolicy
+	heartbeat  *heartbeat
+	// strictRejects returns rejected lines as errors even when they are
+	// passed to the dead-letter sink, for callers that must know
+	strictRejects bool
+	// precision is the one entry timestamps are written with
+	precision time.Duration
+	// telegrafSchema is set by WithTelegrafSyslogSchema
+	telegrafSchema bool
+	// sinkMutex serializes the calls to the dead-letter and fallback sinks,
+	// which chunks written in parallel would otherwise make at once
+	sinkMutex sync.Mutex
 }
 
-func NewLogWriter(connection string, appName, host, procId string, flushInterval time.Duration, bufferLimit int) (*LogWriter, error) {
-	client, err := influxdb3.NewFromConnectionString(connection)
-	if err != nil {
-		return nil, err
-	}
+func NewLogWriter(connection string, appName, host, procId string, flushInterval time.Duration, bufferLimit int, opts ...Option) (*LogWriter, error) {
 	if flushInterval < 0 {
 		return nil, errors.New("invalid flush interval")
 	}
+	var err error
 	writer := &LogWriter{
-		client:        client,
-		flushInterval: flushInterval,
-	}
-	if bufferLimit > 0 {
-		writer.buffer, err = ringqueue.NewUnsafe[*influxdb3.Point](bufferLimit, ringqueue.WhenFullError, ringqueue.WhenEmptyError, nil)
-		if err != nil {
-			return nil, err
-		}
+		measurement:        DefaultMeasurement,
+		appName:            appName,
+		host:               host,
+		tags:               map[logging.Level]map[string]string{},
+		flushInterval:      flushInterval,
+		minLevel:           logging.TraceLevel,
+		multilineSeparator: DefaultMultilineSeparator,
+		timeFieldFormat:    TimestampRFC3339Nano,
+		utf8Replacement:    string(utf8.RuneError),
 	}
+	writer.severityCodes = maps.Clone(severityCode)
 	// initialize tags
 	for level, keyword := range severityMap {
 		writer.tags[level] = map[string]string{
@@ -81,106 +242,560 @@ func NewLogWriter(connection string, appName, host, procId string, flushInterval
 		"timestamp":     0,
 		"version":       1,
 	}
+	for _, opt := range opts {
+		if err := opt(writer); err != nil {
+			return nil, err
+		}
+	}
+	writer.resolveSchema()
+	if err := writer.checkLevelField(); err != nil {
+		return nil, err
+	}
+	switch {
+	case bufferLimit <= 0:
+	case writer.clientBatching:
+		writer.bufferCap = bufferLimit
+		writer.batcher = batching.NewBatcher(batching.WithSize(bufferLimit), batching.WithCapacity(bufferLimit))
+	default:
+		writer.buffer, err = ringqueue.NewUnsafe[*Entry](bufferLimit, ringqueue.WhenFullError, ringqueue.WhenEmptyError, nil)
+		if err != nil {
+			return nil, err
+		}
+		writer.batch = make([]*Entry, 0, bufferLimit)
+		writer.bufferCap = bufferLimit
+	}
+	if writer.backend == nil {
+		writer.backend, err = newBackend(connection, &writer.transport)
+		if err != nil {
+			return nil, err
+		}
+	}
+	writer.precision = time.Nanosecond
+	if b, ok := writer.backend.(*clientBackend); ok {
+		writer.client = b.client
+		if !writer.lineProtocol {
+			writer.precision = b.precision
+		}
+	}
+	if writer.clientBatching && (writer.client == nil || writer.lineProtocol) {
+		_ = writer.backend.Close()
+		return nil, errors.New("client batching requires writing points through the influxdb3 client")
+	}
+	for _, tags := range writer.tags {
+		for key, value := range tags {
+			tags[key] = writer.sanitizeUTF8(value)
+		}
+	}
+	writer.lineTags = make(map[logging.Level][]tag, len(writer.tags))
+	for level, tags := range writer.tags {
+		writer.lineTags[level] = sortedTags(tags)
+	}
+	if writer.multiline == MultilineTag {
+		writer.multilineTags = make(map[logging.Level]map[string]string, len(writer.tags))
+		writer.multilineLineTags = make(map[logging.Level][]tag, len(writer.tags))
+		for level, tags := range writer.tags {
+			tags = maps.Clone(tags)
+			tags["multiline"] = "true"
+			writer.multilineTags[level] = tags
+			writer.multilineLineTags[level] = sortedTags(tags)
+		}
+	}
+	writer.defaultTags = commonTags(writer.tags)
+	writer.pointTags = withoutTags(writer.tags, writer.defaultTags)
+	if writer.multilineTags != nil {
+		writer.multilinePointTags = withoutTags(writer.multilineTags, writer.defaultTags)
+	}
+	writer.ctx, writer.cancel = context.WithCancel(context.Background())
+	writer.done = make(chan struct{})
+	writer.stopped = make(chan struct{})
+	if writer.buffered() {
+		writer.activity = make(chan struct{}, 1)
+		go writer.run()
+	} else {
+		close(writer.stopped)
+	}
+	writer.cacheEnrichers(writer.cachedEnrichers)
+	writer.fieldKeys = newInterner(func(key string) string {
+		return "fields." + key
+	})
+	if writer.sdID != "" {
+		writer.sdKeys = newInterner(func(key string) string {
+			return writer.sdID + "_" + sdName(key)
+		})
+	}
+	if writer.console != nil && !writer.console.levelSet {
+		writer.console.level = writer.minLevel
+	}
+	if writer.errorAlert != nil {
+		writer.OnLevel(logging.ErrorLevel, writer.errorAlert.observe)
+	}
+	if writer.levelMetrics != nil {
+		go writer.runLevelMetrics()
+	}
+	if writer.heartbeat != nil {
+		writer.heartbeat.started = time.Now()
+		go writer.runHeartbeat()
+	}
+	if writer.watermarks != nil && writer.buffered() {
+		go writer.runWatermarks()
+	}
+	if writer.hookQueue != nil {
+		writer.hooksStopped = make(chan struct{})
+		go writer.runHooks()
+	}
 	return writer, nil
 }
 
+// Client returns the influxdb3 client entries are written through, so
+// queries and one-off writes can share its connection. It is nil when
+// entries go through another backend.
+func (w *LogWriter) Client() *influxdb3.Client {
+	return w.client
+}
+
+// Enabled reports whether entries at level are written or filtered out,
+// either to InfluxDB or to the console.
+func (w *LogWriter) Enabled(level logging.Level) bool {
+	return w.written(level) || (w.console != nil && levelRank[level] >= levelRank[w.console.level])
+}
+
+// written reports whether entries at level are written to InfluxDB.
+func (w *LogWriter) written(level logging.Level) bool {
+	return levelRank[level] >= levelRank[w.minLevel]
+}
+
 func (w *LogWriter) Write(level logging.Level, args []any, fields logging.Fields) error {
-	timestamp := time.Now()
-	point := influxdb3.NewPoint(w.measurement, w.tags[level], w.getFields(level, args, fields, timestamp), timestamp)
-	if w.flushInterval == 0 || w.buffer == nil {
-		return w.writePoints(context.Background(), []*influxdb3.Point{point})
+	if !w.Enabled(level) {
+		return nil
 	}
-	return w.writeBuffered(context.Background(), point)
+	// the message is only formatted once the entry is known to be written
+	return w.writeEntry(&Entry{
+		Level:   level,
+		Time:    time.Now(),
+		Message: fmt.Sprint(args...),
+		Fields:  fields,
+	})
 }
 
-func (w *LogWriter) getFields(level logging.Level, args []any, fields logging.Fields, timestamp time.Time) map[string]any {
-	msg := fmt.Sprint(args...)
-	m := map[string]any{}
-	if fields != nil {
-		for key, arg := range fields {
-			m["fields."+key] = arg
+// WriteTemplate writes an entry whose message is template with its
+// {placeholders} filled from fields. The template itself is kept in the
+// template field, so entries of the same kind can be grouped.
+func (w *LogWriter) WriteTemplate(level logging.Level, template string, fields logging.Fields) error {
+	if !w.Enabled(level) {
+		return nil
+	}
+	return w.writeEntry(&Entry{
+		Level:    level,
+		Time:     time.Now(),
+		Message:  renderTemplate(template, fields),
+		Template: template,
+		Fields:   fields,
+	})
+}
+
+func (w *LogWriter) writeEntry(e *Entry) (err error) {
+	defer w.recoverPanic(&err)
+	if w.ctx.Err() != nil {
+		return ErrClosed
+	}
+	w.printEntry(e)
+	if !w.written(e.Level) {
+		return nil
+	}
+	return w.submitEntry(e)
+}
+
+// printEntry cleans up the message of e and prints e to the console, if its
+// level is printed there.
+func (w *LogWriter) printEntry(e *Entry) {
+	e.Message = w.sanitizeUTF8(e.Message)
+	e.Template = w.sanitizeUTF8(e.Template)
+	if w.console != nil && levelRank[e.Level] >= levelRank[w.console.level] {
+		if err := w.console.write(e); err != nil {
+			w.handleError(err)
 		}
 	}
-	for key, value := range w.fields {
+}
+
+// submitEntry writes e, or buffers it to be written by a later flush.
+func (w *LogWriter) submitEntry(e *Entry) error {
+	w.enrich(e)
+	w.normalizeMultiline(e)
+	if w.levelMetrics != nil {
+		w.levelMetrics.count(e)
+	}
+	w.fireHooks(e)
+	entries := w.splitMessage(e)
+	if !w.buffered() {
+		ctx := w.ctx
+		if w.entryDeadline > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, w.entryDeadline)
+			defer cancel()
+		}
+		return w.writeEntries(ctx, entries)
+	}
+	if w.batcher != nil {
+		return w.writeBatched(w.ctx, entries)
+	}
+	return w.writeBuffered(w.ctx, entries)
+}
+
+// buffered reports whether entries are queued and flushed periodically
+// instead of being written one by one.
+func (w *LogWriter) buffered() bool {
+	return w.flushInterval > 0 && (w.buffer != nil || w.batcher != nil)
+}
+
+func (w *LogWriter) getFields(e *Entry) map[string]any {
+	m := map[string]any{}
+	_ = w.eachEntryField(e, func(key string, value any) error {
 		m[key] = value
+		return nil
+	})
+	for key, value := range w.fields {
+		m[key] = w.entryField(e, key, value)
 	}
-	m["severity_code"] = severityCode[level]
-	m["timestamp"] = timestamp.UTC().Format(time.RFC3339)
-	m["message"] = msg
 	return m
 }
 
-func (w *LogWriter) writeBuffered(ctx context.Context, point *influxdb3.Point) error {
+// eachEntryField calls fn with the key and value of every field written for
+// the fields of e, stopping at the first error.
+func (w *LogWriter) eachEntryField(e *Entry, fn func(key string, value any) error) error {
+	if e.Template != "" {
+		if err := fn("template", e.Template); err != nil {
+			return err
+		}
+	}
+	if err := eachChunkField(e, fn); err != nil {
+		return err
+	}
+	if w.sdID != "" {
+		return w.eachStructuredDataField(e, fn)
+	}
+	for key, value := range e.Fields {
+		if err := w.emitField(w.fieldKeys.get(key), value, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// entryField returns the value the writer-level field key takes for e.
+func (w *LogWriter) entryField(e *Entry, key string, value any) any {
+	if key == w.levelField {
+		return levelNames[e.Level]
+	}
+	switch key {
+	case "message":
+		return e.Message
+	case "severity_code":
+		return w.severityCodes[e.Level]
+	case "timestamp":
+		return w.timestampFormat(e.Time)
+	}
+	return value
+}
+
+// writeBuffered adds entries, the chunks of a message, to the buffer,
+// flushing it first if they don't fit. The chunks are buffered together or
+// not at all, so a full buffer never leaves a message partly written.
+func (w *LogWriter) writeBuffered(ctx context.Context, entries []*Entry) error {
 	w.flushMutex.Lock()
 	defer w.flushMutex.Unlock()
-	if w.buffer.Len() == w.buffer.Cap() {
+	defer w.storeBufferLen()
+	if w.buffer.Cap()-w.buffer.Len() < len(entries) {
 		err := w.flushBuffer(ctx)
 		if err != nil {
+			w.drops.bufferFull.Add(uint64(len(entries)))
 			return err
 		}
 	}
-	_, err := w.buffer.Push(point)
-	return err
+	// the buffer stays full while writes are paused
+	if w.buffer.Cap()-w.buffer.Len() < len(entries) {
+		w.drops.bufferFull.Add(uint64(len(entries)))
+		return ringqueue.ErrFullQueue
+	}
+	for _, e := range entries {
+		if _, err := w.buffer.Push(e); err != nil {
+			return err
+		}
+	}
+	return w.notifyFlush(ctx, entries[0])
 }
 
+// flushBuffer drains the buffer into the reusable batch slice and writes it.
+// It must be called with flushMutex held.
 func (w *LogWriter) flushBuffer(ctx context.Context) error {
-	points := make([]*influxdb3.Point, w.buffer.Len())
-	for i := 0; i < w.buffer.Len(); i++ {
-		point, _, err := w.buffer.Pop()
+	defer w.storeBufferLen()
+	if w.paused() {
+		return nil
+	}
+	if w.batcher != nil {
+		return w.flushBatcher(ctx)
+	}
+	n := w.buffer.Len()
+	for i := 0; i < n; i++ {
+		e, _, err := w.buffer.Pop()
 		if err != nil {
 			break
 		}
-		points[i] = point
+		w.batch = append(w.batch, e)
+	}
+	if len(w.batch) == 0 {
+		return nil
+	}
+	err := w.writeEntries(ctx, w.batch)
+	// drop references so written entries can be collected
+	clear(w.batch)
+	w.batch = w.batch[:0]
+	// entries held back by a rate limit go back into the emptied buffer
+	for i, e := range w.requeue {
+		if _, pushErr := w.buffer.Push(e); pushErr != nil {
+			w.drops.rateLimited.Add(uint64(len(w.requeue) - i))
+			err = errors.Join(err, fmt.Errorf("dropped %d rate-limited entries: %w", len(w.requeue)-i, pushErr))
+			break
+		}
 	}
-	return w.writePoints(ctx, points)
+	clear(w.requeue)
+	w.requeue = w.requeue[:0]
+	return err
+}
+
+// writeEntries writes entries in chunks of at most maxBatchSize. A failed
+// chunk doesn't stop the remaining ones from being written.
+func (w *LogWriter) writeEntries(ctx context.Context, entries []*Entry) error {
+	size := w.maxBatchSize
+	if size <= 0 || size >= len(entries) {
+		return w.deliverChunk(ctx, entries)
+	}
+	if w.flushConcurrency > 1 {
+		return w.writeChunksParallel(ctx, entries, size)
+	}
+	var errs []error
+	for start := 0; start < len(entries); start += size {
+		end := min(start+size, len(entries))
+		if err := w.deliverChunk(ctx, entries[start:end]); err != nil {
+			errs = append(errs, fmt.Errorf("writing entries %d-%d: %w", start, end-1, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// writeChunksParallel writes chunks with up to flushConcurrency requests in
+// flight. Chunks may reach the server in any order.
+func (w *LogWriter) writeChunksParallel(ctx context.Context, entries []*Entry, size int) error {
+	errs := make([]error, (len(entries)+size-1)/size)
+	sem := make(chan struct{}, w.flushConcurrency)
+	var wg sync.WaitGroup
+	for start := 0; start < len(entries); start += size {
+		end := min(start+size, len(entries))
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(i int, chunk []*Entry) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			if err := w.deliverChunk(ctx, chunk); err != nil {
+				errs[i] = fmt.Errorf("writing entries %d-%d: %w", start, end-1, err)
+			}
+		}(start/size, entries[start:end])
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// writeChunk writes entries, submitting the valid ones again after a partial
+// write. Entries whose request was rate limited are kept for a later flush.
+func (w *LogWriter) writeChunk(ctx context.Context, entries []*Entry) error {
+	if w.writeTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, w.writeTimeout)
+		defer cancel()
+	}
+	sent, err := w.sendChunk(ctx, entries)
+	if retryAfter, ok := rateLimited(err); ok {
+		// entries left out of the request were already rejected
+		return w.backOff(sent, err, retryAfter)
+	}
+	valid, ok := w.salvage(sent, err)
+	if !ok {
+		return err
+	}
+	var retryErr error
+	if len(valid) > 0 {
+		_, retryErr = w.sendChunk(ctx, valid)
+		if retryAfter, ok := rateLimited(retryErr); ok {
+			// only the valid entries are kept, the rejected ones are done
+			retryErr = w.backOff(valid, retryErr, retryAfter)
+		}
+	}
+	if w.deadLetter == nil || w.strictRejects {
+		// without a dead-letter sink the rejection is the only trace left
+		return errors.Join(err, retryErr)
+	}
+	return retryErr
+}
+
+// newPoint returns the point e is written as. Points share the interned tags
+// of their level rather than copying them as influxdb3.NewPoint does, since
+// the client only reads them.
+func (w *LogWriter) newPoint(e *Entry) *influxdb3.Point {
+	return influxdb3.NewPointWithPointValues(&influxdb3.PointValues{
+		MeasurementName: w.measurement,
+		Tags:            w.entryTags(e),
+		Fields:          w.getFields(e),
+		Timestamp:       e.Time,
+	})
+}
+
+// sendChunk writes entries in a single request and returns the entries that
+// made it into the request, in order. A panic while writing, such as one
+// raised by a faulty field value, fails the whole chunk with the panic as
+// its error.
+func (w *LogWriter) sendChunk(ctx context.Context, entries []*Entry) (sent []*Entry, err error) {
+	defer func() {
+		r := recover()
+		if r == nil {
+			return
+		}
+		sent, err = entries, w.panicError(r)
+		w.recordWrite(len(entries), err)
+		w.fallBack(entries, err)
+	}()
+	if w.lineProtocol || w.client == nil {
+		return w.writeLines(ctx, entries)
+	}
+	points := make([]*influxdb3.Point, len(entries))
+	for i, e := range entries {
+		points[i] = w.newPoint(e)
+	}
+	err = w.writePoints(ctx, points)
+	w.fallBack(entries, err)
+	return entries, err
 }
 
 func (w *LogWriter) writePoints(ctx context.Context, points []*influxdb3.Point) error {
-	return w.client.WritePoints(ctx, points)
+	err := clientWrite(ctx, func(ctx context.Context) error {
+		return w.client.WritePoints(ctx, points, influxdb3.WithDefaultTags(w.defaultTags))
+	})
+	w.recordWrite(len(points), err)
+	return err
 }
 
+func (w *LogWriter) writeLines(ctx context.Context, entries []*Entry) ([]*Entry, error) {
+	enc, _ := w.encoders.Get().(*lineprotocol.Encoder)
+	if enc == nil {
+		enc = &lineprotocol.Encoder{}
+	}
+	defer w.encoders.Put(enc)
+	enc.Reset()
+	sent := make([]*Entry, 0, len(entries))
+	var errs []error
+	for _, e := range entries {
+		// a malformed entry is left out of the batch instead of failing it
+		if err := w.encodeEntry(enc, e); err != nil {
+			w.rejectEntry(e, err)
+			errs = append(errs, err)
+			continue
+		}
+		sent = append(sent, e)
+	}
+	if len(sent) > 0 {
+		err := w.backend.WriteLineProtocol(ctx, enc.Bytes())
+		w.recordWrite(len(sent), err)
+		w.fallBack(sent, err)
+		errs = append(errs, err)
+	}
+	return sent, errors.Join(errs...)
+}
+
+// Logger is a logging.Logger writing through a LogWriter. Loggers are
+// immutable: deriving one with WithFields or WithAdditionalFields copies
+// the fields, so loggers can be used and derived from concurrently, and
+// later changes to a map passed in don't affect them.
 type Logger struct {
 	writer *LogWriter
+	// fields is never modified once the logger is created, which lets
+	// entries share it
 	fields logging.Fields
 }
 
 func (l *Logger) Log(level logging.Level, args ...interface{}) {
 	_ = l.writer.Write(level, args, l.fields)
+	l.terminate(level, func() string { return fmt.Sprint(args...) })
+}
+
+// LogT logs a message rendered from template, where every {name}
+// placeholder is replaced with the value of the field name. fields are
+// added to the fields of the logger for this entry only, and the raw
+// template is stored alongside the message.
+func (l *Logger) LogT(level logging.Level, template string, fields logging.Fields) {
+	merged := make(logging.Fields, len(l.fields)+len(fields))
+	maps.Copy(merged, l.fields)
+	maps.Copy(merged, fields)
+	_ = l.writer.WriteTemplate(level, template, merged)
+	l.terminate(level, func() string { return renderTemplate(template, merged) })
+}
 
+// terminate exits or panics after logging at the fatal or panic level.
+// Before exiting, it gives queued hooks a bounded time to run.
+func (l *Logger) terminate(level logging.Level, message func() string) {
 	if level == logging.FatalLevel {
+		l.writer.drainHooks(hookDrainTimeout)
+		_ = l.writer.Flush(context.Background())
 		os.Exit(1)
 	}
 	if level == logging.PanicLevel {
-		panic(fmt.Sprint(args...))
+		panic(message())
 	}
 }
 
 func (l *Logger) WithFields(fields logging.Fields) logging.Logger {
 	return &Logger{
 		writer: l.writer,
-		fields: fields,
+		fields: maps.Clone(fields),
 	}
 }
 
+// WithAdditionalFields derives a logger with the fields of l and fields,
+// where fields take precedence. Neither map is modified.
 func (l *Logger) WithAdditionalFields(fields logging.Fields) logging.Logger {
-	merged := fields
-	for k, v := range l.fields {
-		if _, ok := merged[k]; !ok {
-			merged[k] = v
-		}
+	merged := make(logging.Fields, len(l.fields)+len(fields))
+	maps.Copy(merged, l.fields)
+	maps.Copy(merged, fields)
+	return &Logger{
+		writer: l.writer,
+		fields: merged,
 	}
-	return l.WithFields(merged)
+}
+
+// Writer returns the writer shared by l and every logger derived from it.
+func (l *Logger) Writer() *LogWriter {
+	return l.writer
 }
 
 func (l *Logger) Logger() logging.Logger {
 	return &Logger{writer: l.writer}
 }
 
-func NewLogger(connection, appName, host, procId string) (logging.Logger, error) {
-	return NewBufferedLogger(connection, appName, host, procId, 0, 0)
+// Close flushes pending entries and closes the underlying writer, which is
+// shared with every logger derived from l.
+func (l *Logger) Close() error {
+	return l.writer.Close()
+}
+
+// Shutdown is like Close, but gives up on pending entries once ctx is done.
+func (l *Logger) Shutdown(ctx context.Context) error {
+	return l.writer.Shutdown(ctx)
+}
+
+func NewLogger(connection, appName, host, procId string, opts ...Option) (logging.Logger, error) {
+	return NewBufferedLogger(connection, appName, host, procId, 0, 0, opts...)
 }
-func NewBufferedLogger(connection string, appName, host, procId string, flushInterval time.Duration, bufferLimit int) (*Logger, error) {
-	writer, err := NewLogWriter(connection, appName, host, procId, flushInterval, bufferLimit)
+func NewBufferedLogger(connection string, appName, host, procId string, flushInterval time.Duration, bufferLimit int, opts ...Option) (*Logger, error) {
+	writer, err := NewLogWriter(connection, appName, host, procId, flushInterval, bufferLimit, opts...)
 	if err != nil {
 		return nil, err
 	}
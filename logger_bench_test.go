@@ -0,0 +1,169 @@
+package influxlogger
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/InfluxCommunity/influxdb3-go/v2/influxdb3"
+	"github.com/hadi77ir/go-logging"
+	"github.com/influxdata/line-protocol/v2/lineprotocol"
+)
+
+// newBenchServer starts a server that accepts and discards every write.
+func newBenchServer(b *testing.B) *httptest.Server {
+	b.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		rw.WriteHeader(http.StatusNoContent)
+	}))
+	b.Cleanup(server.Close)
+	return server
+}
+
+func benchmarkBufferedWrite(b *testing.B, bufferLimit int, opts ...Option) {
+	server := newBenchServer(b)
+	writer, err := NewLogWriter(server.URL+"?token=bench&database=logs", "bench", "localhost", "1", time.Minute, bufferLimit, opts...)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() { _ = writer.Close() })
+	args := []any{"request served"}
+	fields := logging.Fields{"status": 200, "path": "/index"}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := writer.Write(logging.InfoLevel, args, fields); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkBufferedWrite(b *testing.B) {
+	b.Run("points", func(b *testing.B) {
+		benchmarkBufferedWrite(b, 1000)
+	})
+	b.Run("lineprotocol", func(b *testing.B) {
+		benchmarkBufferedWrite(b, 1000, WithLineProtocol())
+	})
+}
+
+func BenchmarkFlushBuffer(b *testing.B) {
+	const bufferLimit = 1000
+	server := newBenchServer(b)
+	writer, err := NewLogWriter(server.URL+"?token=bench&database=logs", "bench", "localhost", "1", time.Minute, bufferLimit, WithLineProtocol())
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() { _ = writer.Close() })
+	e := &Entry{Level: logging.InfoLevel, Time: time.Now(), Message: "request served"}
+	ctx := context.Background()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		for j := 0; j < bufferLimit; j++ {
+			if _, err := writer.buffer.Push(e); err != nil {
+				b.Fatal(err)
+			}
+		}
+		b.StartTimer()
+		writer.flushMutex.Lock()
+		err := writer.flushBuffer(ctx)
+		writer.flushMutex.Unlock()
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkPointTags compares building and serializing a batch of points
+// carrying every tag with one leaving the static tags to default tags. The
+// serialized size is reported per point.
+func BenchmarkPointTags(b *testing.B) {
+	const batchSize = 1000
+	writer, err := NewLogWriter("http://localhost:8181?token=bench&database=logs", "bench", "localhost", "1", 0, 0)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() { _ = writer.Close() })
+	e := &Entry{Level: logging.InfoLevel, Time: time.Now(), Message: "request served"}
+	run := func(b *testing.B, tags map[string]string, defaultTags map[string]string) {
+		var size int
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			size = 0
+			for j := 0; j < batchSize; j++ {
+				point := influxdb3.NewPoint(writer.measurement, tags, writer.getFields(e), e.Time)
+				line, err := point.MarshalBinaryWithDefaultTags(lineprotocol.Nanosecond, defaultTags)
+				if err != nil {
+					b.Fatal(err)
+				}
+				size += len(line)
+			}
+		}
+		b.ReportMetric(float64(size)/batchSize, "B/point")
+	}
+	b.Run("per-point", func(b *testing.B) {
+		run(b, writer.tags[e.Level], nil)
+	})
+	b.Run("default", func(b *testing.B) {
+		run(b, writer.entryTags(e), writer.defaultTags)
+	})
+}
+
+// BenchmarkNewPoint compares building a point that copies the tags of its
+// level with one sharing them.
+func BenchmarkNewPoint(b *testing.B) {
+	writer, err := NewLogWriter("http://localhost:8181?token=bench&database=logs", "bench", "localhost", "1", 0, 0)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() { _ = writer.Close() })
+	e := &Entry{Level: logging.InfoLevel, Time: time.Now(), Message: "request served"}
+	var sink *influxdb3.Point
+	b.Run("copied", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			sink = influxdb3.NewPoint(writer.measurement, writer.entryTags(e), writer.getFields(e), e.Time)
+		}
+	})
+	b.Run("shared", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			sink = writer.newPoint(e)
+		}
+	})
+	_ = sink
+}
+
+// BenchmarkFieldKeys compares building the name of every entry field per
+// entry with looking it up in the interner.
+func BenchmarkFieldKeys(b *testing.B) {
+	keys := []string{"status", "path", "method", "duration", "user"}
+	var sink string
+	b.Run("concat", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			for _, key := range keys {
+				sink = "fields." + key
+			}
+		}
+	})
+	b.Run("interned", func(b *testing.B) {
+		in := newInterner(func(key string) string {
+			return "fields." + key
+		})
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			for _, key := range keys {
+				sink = in.get(key)
+			}
+		}
+	})
+	_ = sink
+}
@@ -0,0 +1,343 @@
+package influxlogger
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hadi77ir/go-logging"
+	"github.com/influxdata/line-protocol/v2/lineprotocol"
+)
+
+// request is a request received by a testServer.
+type request struct {
+	method string
+	path   string
+	query  url.Values
+	header http.Header
+	body   string
+}
+
+// testServer records the requests it receives and answers the nth of them
+// with respond.
+type testServer struct {
+	*httptest.Server
+	mutex    sync.Mutex
+	requests []request
+}
+
+func newTestServer(t *testing.T, respond func(rw http.ResponseWriter, r request, n int)) *testServer {
+	t.Helper()
+	s := &testServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		req := request{method: r.Method, path: r.URL.Path, query: r.URL.Query(), header: r.Header, body: string(body)}
+		s.mutex.Lock()
+		s.requests = append(s.requests, req)
+		n := len(s.requests)
+		s.mutex.Unlock()
+		respond(rw, req, n)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// received returns the requests received so far.
+func (s *testServer) received() []request {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return slices.Clone(s.requests)
+}
+
+// newTestWriter creates a writer that is only flushed when asked to, and
+// closed at the end of the test.
+func newTestWriter(t *testing.T, connection string, bufferLimit int, opts ...Option) *LogWriter {
+	t.Helper()
+	writer, err := NewLogWriter(connection, "test", "localhost", "1", time.Hour, bufferLimit, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = writer.Close() })
+	return writer
+}
+
+// line is a decoded line of line protocol.
+type line struct {
+	measurement string
+	tags        map[string]string
+	fields      map[string]any
+	time        time.Time
+}
+
+func decodeLines(t *testing.T, body string) []line {
+	t.Helper()
+	var lines []line
+	dec := lineprotocol.NewDecoderWithBytes([]byte(body))
+	for dec.Next() {
+		measurement, err := dec.Measurement()
+		if err != nil {
+			t.Fatal(err)
+		}
+		l := line{measurement: string(measurement), tags: map[string]string{}, fields: map[string]any{}}
+		for {
+			key, value, err := dec.NextTag()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if key == nil {
+				break
+			}
+			l.tags[string(key)] = string(value)
+		}
+		for {
+			key, value, err := dec.NextField()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if key == nil {
+				break
+			}
+			l.fields[string(key)] = value.Interface()
+		}
+		if l.time, err = dec.Time(lineprotocol.Nanosecond, time.Time{}); err != nil {
+			t.Fatal(err)
+		}
+		lines = append(lines, l)
+	}
+	return lines
+}
+
+// messages returns the message of every line of body.
+func messages(t *testing.T, body string) []string {
+	t.Helper()
+	var result []string
+	for _, l := range decodeLines(t, body) {
+		message, _ := l.fields["message"].(string)
+		result = append(result, message)
+	}
+	return result
+}
+
+// recordingBackend keeps the line protocol written to it.
+type recordingBackend struct {
+	mutex sync.Mutex
+	lines strings.Builder
+}
+
+func (b *recordingBackend) WriteLineProtocol(ctx context.Context, lines []byte) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.lines.Write(lines)
+	return nil
+}
+
+func (b *recordingBackend) Close() error {
+	return nil
+}
+
+// written returns the lines written so far.
+func (b *recordingBackend) written(t *testing.T) []line {
+	t.Helper()
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return decodeLines(t, b.lines.String())
+}
+
+// writtenLines creates an unbuffered writer with opts, calls write with it
+// and returns the lines it wrote.
+func writtenLines(t *testing.T, write func(w *LogWriter), opts ...Option) []line {
+	t.Helper()
+	backend := &recordingBackend{}
+	writer := newTestWriter(t, "", 0, append(opts, WithBackend(backend))...)
+	write(writer)
+	return backend.written(t)
+}
+
+// writtenLine is like writtenLines for a single entry written at level
+// with message and fields.
+func writtenLine(t *testing.T, level logging.Level, message string, fields logging.Fields, opts ...Option) line {
+	t.Helper()
+	lines := writtenLines(t, func(w *LogWriter) {
+		if err := w.Write(level, []any{message}, fields); err != nil {
+			t.Fatal(err)
+		}
+	}, opts...)
+	if len(lines) != 1 {
+		t.Fatalf("got %d lines, want 1", len(lines))
+	}
+	return lines[0]
+}
+
+// optionsError returns the error of creating a writer with opts, which
+// only opts can fail.
+func optionsError(opts ...Option) error {
+	writer, err := NewLogWriter("", "test", "localhost", "1", 0, 0, append(opts, WithBackend(&recordingBackend{}))...)
+	if err == nil {
+		_ = writer.Close()
+	}
+	return err
+}
+
+func writeMessages(t *testing.T, writer *LogWriter, messages ...string) {
+	t.Helper()
+	for _, message := range messages {
+		if err := writer.Write(logging.InfoLevel, []any{message}, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestParallelChunkSinks(t *testing.T) {
+	// the first line of every chunk is rejected, and resent chunks accepted
+	server := newTestServer(t, func(rw http.ResponseWriter, r request, n int) {
+		if strings.Count(r.body, "\n") < 2 {
+			rw.WriteHeader(http.StatusNoContent)
+			return
+		}
+		rw.WriteHeader(http.StatusBadRequest)
+		_, _ = io.WriteString(rw, `{"error":"partial write of line protocol occurred","data":[`+
+			`{"original_line":"","line_number":1,"error_message":"invalid column type"}]}`)
+	})
+	// the sink isn't safe for concurrent use, which the race detector
+	// reports if it is called concurrently
+	var rejected []string
+	writer := newTestWriter(t, "lp+"+server.URL+"/write", 20,
+		WithMaxBatchSize(2),
+		WithFlushConcurrency(4),
+		WithDeadLetter(func(e Entry, reason error) {
+			rejected = append(rejected, e.Message)
+		}))
+	var written []string
+	for i := range 16 {
+		written = append(written, fmt.Sprint(i))
+	}
+	writeMessages(t, writer, written...)
+	if err := writer.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(rejected)
+	var want []string
+	for i := 0; i < len(written); i += 2 {
+		want = append(want, written[i])
+	}
+	slices.Sort(want)
+	if !slices.Equal(rejected, want) {
+		t.Errorf("dead-lettered %q, want %q", rejected, want)
+	}
+	if n := len(server.received()); n != len(written) {
+		t.Errorf("got %d requests, want %d", n, len(written))
+	}
+}
+
+// panicBackend panics on every write.
+type panicBackend struct{}
+
+func (panicBackend) WriteLineProtocol(ctx context.Context, lines []byte) error {
+	panic("backend failed")
+}
+
+func (panicBackend) Close() error {
+	return nil
+}
+
+func TestPanicRecovery(t *testing.T) {
+	tests := []struct {
+		name        string
+		bufferLimit int
+	}{
+		{"unbuffered", 0},
+		{"buffered", 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mutex sync.Mutex
+			var handled []error
+			writer := newTestWriter(t, "", tt.bufferLimit,
+				WithBackend(panicBackend{}),
+				WithErrorHandler(func(err error) {
+					mutex.Lock()
+					defer mutex.Unlock()
+					handled = append(handled, err)
+				}))
+			err := writer.Write(logging.InfoLevel, []any{"hello"}, nil)
+			if tt.bufferLimit > 0 {
+				if err != nil {
+					t.Fatal(err)
+				}
+				err = writer.Flush(context.Background())
+			}
+			if err == nil || !strings.Contains(err.Error(), "recovered from panic") {
+				t.Errorf("got error %v, want the recovered panic", err)
+			}
+			mutex.Lock()
+			defer mutex.Unlock()
+			if len(handled) == 0 || !strings.Contains(handled[0].Error(), "backend failed") {
+				t.Errorf("got handled errors %v", handled)
+			}
+		})
+	}
+}
+
+func TestDerivedLoggers(t *testing.T) {
+	backend := &recordingBackend{}
+	writer := newTestWriter(t, "", 0, WithBackend(backend))
+	fields := logging.Fields{"service": "api"}
+	base := (&Logger{writer: writer}).WithFields(fields)
+	// changes to the map passed in don't reach the logger
+	fields["service"] = "changed"
+	derived := base.WithAdditionalFields(logging.Fields{"request": "1"})
+	other := derived.WithAdditionalFields(logging.Fields{"request": "2"})
+	var wg sync.WaitGroup
+	for _, logger := range []logging.Logger{base, derived, other} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			logger.Log(logging.InfoLevel, "hello")
+		}()
+	}
+	wg.Wait()
+	requests := map[any]int{}
+	for _, l := range backend.written(t) {
+		if l.fields["fields.service"] != "api" {
+			t.Errorf("got service %#v, want api", l.fields["fields.service"])
+		}
+		requests[l.fields["fields.request"]]++
+	}
+	want := map[any]int{nil: 1, "1": 1, "2": 1}
+	if !maps.Equal(requests, want) {
+		t.Errorf("got requests %v, want %v", requests, want)
+	}
+}
+
+func TestAccessors(t *testing.T) {
+	logger, err := NewBufferedLogger("", "test", "localhost", "1", time.Hour, 10, WithBackend(&recordingBackend{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = logger.Close() })
+	derived := logger.WithFields(logging.Fields{"a": 1}).WithAdditionalFields(logging.Fields{"b": 2})
+	if derived.(*Logger).Writer() != logger.Writer() {
+		t.Error("expected derived loggers to share the writer")
+	}
+	// only the InfluxDB 3 backend goes through the client
+	if client := logger.Writer().Client(); client != nil {
+		t.Errorf("got client %v for a custom backend", client)
+	}
+	server := newTestServer(t, func(rw http.ResponseWriter, r request, n int) {
+		rw.WriteHeader(http.StatusNoContent)
+	})
+	for _, connection := range []string{"lp+" + server.URL + "/write", "influxdb1+" + server.URL + "?db=logs"} {
+		if client := newTestWriter(t, connection, 0).Client(); client != nil {
+			t.Errorf("got client %v for %s", client, connection)
+		}
+	}
+}
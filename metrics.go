@@ -0,0 +1,116 @@
+package influxlogger
+
+import (
+	"context"
+	"maps"
+	"sync"
+	"time"
+
+	"github.com/hadi77ir/go-logging"
+	"github.com/influxdata/line-protocol/v2/lineprotocol"
+)
+
+// levelCountKey identifies one series of the level metrics measurement.
+type levelCountKey struct {
+	level  logging.Level
+	logger string
+}
+
+// levelMetrics counts logged entries per level and logger name, and is
+// written out as one point per series every interval.
+type levelMetrics struct {
+	measurement string
+	interval    time.Duration
+	// nameField is the entry field holding the logger name, if any
+	nameField string
+	mutex     sync.Mutex
+	counts    map[levelCountKey]int64
+	stopped   chan struct{}
+}
+
+func (m *levelMetrics) count(e *Entry) {
+	key := levelCountKey{level: e.Level}
+	if m.nameField != "" {
+		key.logger, _ = e.Fields[m.nameField].(string)
+	}
+	m.mutex.Lock()
+	m.counts[key]++
+	m.mutex.Unlock()
+}
+
+// runLevelMetrics writes the level counts every interval until the writer
+// is closed, then writes whatever was counted since the last tick. Ticks
+// during a pause are skipped, so the counts are written once it is over.
+func (w *LogWriter) runLevelMetrics() {
+	defer close(w.levelMetrics.stopped)
+	ticker := time.NewTicker(w.levelMetrics.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if w.paused() {
+				continue
+			}
+			if err := w.writeLevelMetrics(w.ctx); err != nil {
+				w.handleError(err)
+			}
+		case <-w.done:
+			ctx, cancel := context.WithTimeout(context.Background(), defaultHTTPTimeout)
+			if err := w.writeLevelMetrics(ctx); err != nil {
+				w.handleError(err)
+			}
+			cancel()
+			return
+		}
+	}
+}
+
+// writeLevelMetrics writes and resets the level counts. Series that saw no
+// entries since the last write are left out. If the write fails, the counts
+// are kept for the next one, and a rate-limited write pauses writes.
+func (w *LogWriter) writeLevelMetrics(ctx context.Context) (err error) {
+	defer w.recoverPanic(&err)
+	m := w.levelMetrics
+	m.mutex.Lock()
+	counts := m.counts
+	m.counts = make(map[levelCountKey]int64, len(counts))
+	m.mutex.Unlock()
+	if len(counts) == 0 {
+		return nil
+	}
+	now := time.Now()
+	enc := &lineprotocol.Encoder{}
+	for key, count := range counts {
+		tags := w.tags[key.level]
+		if key.logger != "" {
+			tags = maps.Clone(tags)
+			tags["logger"] = w.sanitizeUTF8(key.logger)
+		}
+		enc.StartLine(m.measurement)
+		for _, t := range sortedTags(tags) {
+			enc.AddTag(t.key, t.value)
+		}
+		enc.AddField("count", lineprotocol.IntValue(count))
+		enc.EndLine(now)
+	}
+	if err := enc.Err(); err != nil {
+		return err
+	}
+	if err := w.backend.WriteLineProtocol(ctx, enc.Bytes()); err != nil {
+		if retryAfter, ok := rateLimited(err); ok {
+			w.pause(retryAfter)
+		}
+		m.restore(counts)
+		return err
+	}
+	return nil
+}
+
+// restore adds counts that failed to be written back to the current ones.
+func (m *levelMetrics) restore(counts map[levelCountKey]int64) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	for key, count := range counts {
+		m.counts[key] += count
+	}
+}
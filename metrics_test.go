@@ -0,0 +1,88 @@
+package influxlogger
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/hadi77ir/go-logging"
+)
+
+// levelCounts returns the counts written in body by level tag.
+func levelCounts(t *testing.T, body string) map[string]any {
+	t.Helper()
+	counts := map[string]any{}
+	for _, l := range decodeLines(t, body) {
+		if l.measurement != "log_levels" {
+			t.Errorf("got measurement %q", l.measurement)
+		}
+		counts[l.tags["severity"]] = l.fields["count"]
+	}
+	return counts
+}
+
+func TestLevelMetricsFailedWrite(t *testing.T) {
+	server := newTestServer(t, func(rw http.ResponseWriter, r request, n int) {
+		if n == 1 {
+			rw.Header().Set("Retry-After", "1")
+			rw.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		rw.WriteHeader(http.StatusNoContent)
+	})
+	// entries stay buffered, so only level counts are written
+	writer := newTestWriter(t, "lp+"+server.URL+"/write", 10, WithLevelMetrics("log_levels", time.Hour, ""))
+	writeMessages(t, writer, "a", "b")
+	if err := writer.writeLevelMetrics(context.Background()); err == nil {
+		t.Fatal("expected the first write to fail")
+	}
+	if !writer.paused() {
+		t.Error("expected the rate-limited write to pause writes")
+	}
+	if err := writer.Write(logging.ErrorLevel, []any{"c"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.writeLevelMetrics(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	requests := server.received()
+	if len(requests) != 2 {
+		t.Fatalf("got %d requests, want 2", len(requests))
+	}
+	// the counts of the failed write are carried over
+	got := levelCounts(t, requests[1].body)
+	if len(got) != 2 || got["info"] != int64(2) || got["err"] != int64(1) {
+		t.Errorf("got counts %v, want 2 info and 1 err", got)
+	}
+}
+
+func TestLevelMetricsPaused(t *testing.T) {
+	server := newTestServer(t, func(rw http.ResponseWriter, r request, n int) {
+		rw.WriteHeader(http.StatusNoContent)
+	})
+	writer, err := NewLogWriter("lp+"+server.URL+"/write", "test", "localhost", "1", time.Hour, 10,
+		WithLevelMetrics("log_levels", 5*time.Millisecond, ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	writer.pause(time.Hour)
+	writeMessages(t, writer, "a")
+	time.Sleep(50 * time.Millisecond)
+	if n := len(server.received()); n != 0 {
+		t.Fatalf("got %d requests during the pause, want none", n)
+	}
+	// closing writes the counts kept during the pause
+	_ = writer.Close()
+	counts := map[string]any{}
+	for _, r := range server.received() {
+		for _, l := range decodeLines(t, r.body) {
+			if l.measurement == "log_levels" {
+				counts[l.tags["severity"]] = l.fields["count"]
+			}
+		}
+	}
+	if len(counts) != 1 || counts["info"] != int64(1) {
+		t.Errorf("got counts %v, want 1 info", counts)
+	}
+}
@@ -0,0 +1,61 @@
+package influxlogger
+
+import "strings"
+
+// MultilinePolicy decides how messages spanning several lines, such as
+// stack traces, are written.
+type MultilinePolicy int
+
+const (
+	// MultilineKeep writes messages as they are. It is the default.
+	MultilineKeep MultilinePolicy = iota
+	// MultilineReplace replaces line breaks with a visible separator.
+	MultilineReplace
+	// MultilineTag writes messages as they are, but tags multi-line ones
+	// with multiline=true.
+	MultilineTag
+)
+
+// DefaultMultilineSeparator replaces line breaks under MultilineReplace
+// unless another separator is set.
+const DefaultMultilineSeparator = `\n`
+
+var lineBreaks = strings.NewReplacer("\r\n", "\n", "\r", "\n")
+
+// normalizeMultiline applies the multi-line policy to the message of e.
+func (w *LogWriter) normalizeMultiline(e *Entry) {
+	if w.multiline == MultilineKeep || !strings.ContainsAny(e.Message, "\r\n") {
+		return
+	}
+	switch w.multiline {
+	case MultilineReplace:
+		message := lineBreaks.Replace(strings.TrimRight(e.Message, "\r\n"))
+		e.Message = strings.ReplaceAll(message, "\n", w.multilineSeparator)
+	case MultilineTag:
+		e.multiline = true
+	}
+}
+
+// allEntryTags returns every tag of e, including default tags.
+func (w *LogWriter) allEntryTags(e *Entry) map[string]string {
+	if e.multiline {
+		return w.multilineTags[e.Level]
+	}
+	return w.tags[e.Level]
+}
+
+// entryTags returns the tags of e that aren't written as default tags.
+func (w *LogWriter) entryTags(e *Entry) map[string]string {
+	if e.multiline {
+		return w.multilinePointTags[e.Level]
+	}
+	return w.pointTags[e.Level]
+}
+
+// entryLineTags returns the tags of e in line protocol order.
+func (w *LogWriter) entryLineTags(e *Entry) []tag {
+	if e.multiline {
+		return w.multilineLineTags[e.Level]
+	}
+	return w.lineTags[e.Level]
+}
@@ -0,0 +1,38 @@
+package influxlogger
+
+import (
+	"testing"
+
+	"github.com/hadi77ir/go-logging"
+)
+
+func TestMultilinePolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    []Option
+		message string
+		want    string
+		tagged  bool
+	}{
+		{"keep", nil, "panic\n\tat main\n", "panic\n\tat main\n", false},
+		{"replace", []Option{WithMultilinePolicy(MultilineReplace)}, "panic\r\n\tat main\r\n", `panic\n` + "\tat main", false},
+		{"replace mixed", []Option{WithMultilinePolicy(MultilineReplace)}, "a\rb\nc\r\nd", `a\nb\nc\nd`, false},
+		{"separator", []Option{WithMultilinePolicy(MultilineReplace), WithMultilineSeparator(" | ")}, "a\nb", "a | b", false},
+		{"tag", []Option{WithMultilinePolicy(MultilineTag)}, "a\nb", "a\nb", true},
+		{"tag single line", []Option{WithMultilinePolicy(MultilineTag)}, "a b", "a b", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := writtenLine(t, logging.InfoLevel, tt.message, nil, tt.opts...)
+			if l.fields["message"] != tt.want {
+				t.Errorf("got message %q, want %q", l.fields["message"], tt.want)
+			}
+			if got, ok := l.tags["multiline"]; ok != tt.tagged || (ok && got != "true") {
+				t.Errorf("got multiline tag %q (%t), want it set: %t", got, ok, tt.tagged)
+			}
+		})
+	}
+	if err := optionsError(WithMultilinePolicy(MultilineTag + 1)); err == nil {
+		t.Error("expected an invalid policy to be rejected")
+	}
+}
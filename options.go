@@ -0,0 +1,752 @@
+package influxlogger
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/hadi77ir/go-logging"
+)
+
+// Option configures optional behavior of a LogWriter.
+type Option func(w *LogWriter) error
+
+// WithLineProtocol makes the writer serialize entries straight to line
+// protocol in a reusable buffer and write them as raw batches, skipping
+// influxdb3.Point construction. It is meant for high-volume writers.
+func WithLineProtocol() Option {
+	return func(w *LogWriter) error {
+		w.lineProtocol = true
+		return nil
+	}
+}
+
+// WithLevel drops entries less severe than level before their message is
+// formatted, so filtered-out calls cost next to nothing.
+func WithLevel(level logging.Level) Option {
+	return func(w *LogWriter) error {
+		if _, ok := levelRank[level]; !ok {
+			return errors.New("invalid level")
+		}
+		w.minLevel = level
+		return nil
+	}
+}
+
+// WithMaxBatchSize splits flushes into write requests of at most size
+// entries, so a large backlog doesn't exceed the server's request limits.
+func WithMaxBatchSize(size int) Option {
+	return func(w *LogWriter) error {
+		if size < 0 {
+			return errors.New("invalid max batch size")
+		}
+		w.maxBatchSize = size
+		return nil
+	}
+}
+
+// WithFlushConcurrency lets a flush write up to n chunks at once, trading
+// the order of writes for faster recovery from a large backlog. It only has
+// an effect together with WithMaxBatchSize.
+func WithFlushConcurrency(n int) Option {
+	return func(w *LogWriter) error {
+		if n < 1 {
+			return errors.New("invalid flush concurrency")
+		}
+		w.flushConcurrency = n
+		return nil
+	}
+}
+
+// WithIdleFlush flushes the buffer once no new entries have arrived for d,
+// so the last lines of a quiet service aren't held until the next periodic
+// flush.
+func WithIdleFlush(d time.Duration) Option {
+	return func(w *LogWriter) error {
+		if d < 0 {
+			return errors.New("invalid idle flush duration")
+		}
+		w.idleFlush = d
+		return nil
+	}
+}
+
+// WithFlushJitter delays each periodic flush by a random duration of up to
+// max, smoothing the write load of many instances started at the same time.
+func WithFlushJitter(max time.Duration) Option {
+	return func(w *LogWriter) error {
+		if max < 0 {
+			return errors.New("invalid flush jitter")
+		}
+		w.flushJitter = max
+		return nil
+	}
+}
+
+// WithWriteTimeout bounds every write request to InfluxDB by d, so a hung
+// connection can't hold up the flush, and with it every logging goroutine,
+// indefinitely.
+func WithWriteTimeout(d time.Duration) Option {
+	return func(w *LogWriter) error {
+		if d < 0 {
+			return errors.New("invalid write timeout")
+		}
+		w.writeTimeout = d
+		return nil
+	}
+}
+
+// WithEntryDeadline bounds the time a logging call of an unbuffered writer
+// spends writing its entry by d, including any resubmission. An entry that
+// misses its deadline is handed to the fallback sink, or to the dead-letter
+// sink if there is none, and the call returns the error.
+func WithEntryDeadline(d time.Duration) Option {
+	return func(w *LogWriter) error {
+		if d < 0 {
+			return errors.New("invalid entry deadline")
+		}
+		w.entryDeadline = d
+		return nil
+	}
+}
+
+// WithErrorHandler sets a function receiving errors that can't be returned
+// to a caller, such as failures of the periodic flush.
+func WithErrorHandler(handler func(error)) Option {
+	return func(w *LogWriter) error {
+		w.errorHandler = handler
+		return nil
+	}
+}
+
+// WithDeadLetter sets a sink for entries that can never be written, such as
+// the lines rejected by the server in a partial write, together with the
+// reason they were rejected. Without a fallback sink, it also receives the
+// entries whose write timed out. The sink is never called concurrently, but
+// it must not write through the writer itself.
+func WithDeadLetter(sink func(e Entry, reason error)) Option {
+	return func(w *LogWriter) error {
+		w.deadLetter = sink
+		return nil
+	}
+}
+
+// WithDeadLetterJSON writes entries that can never be written to out as
+// JSON lines, with the reason they were rejected. See JSONLinesEncoder.
+func WithDeadLetterJSON(out io.Writer) Option {
+	return func(w *LogWriter) error {
+		w.deadLetter = w.JSONLinesEncoder(out).sink()
+		return nil
+	}
+}
+
+// WithFallback sets a sink for entries lost to a failed write, such as one
+// that timed out or found the server unreachable, together with the error
+// of the write. Rate-limited entries of buffered writers are kept for a
+// later write instead; unbuffered writers can't keep them, so they are
+// handed to the sink as well. Like the dead-letter sink, it is never called
+// concurrently and must not write through the writer itself.
+func WithFallback(sink func(e Entry, reason error)) Option {
+	return func(w *LogWriter) error {
+		w.fallback = sink
+		return nil
+	}
+}
+
+// WithFallbackJSON writes entries lost to a failed write to out as JSON
+// lines, with the error of the write. See JSONLinesEncoder.
+func WithFallbackJSON(out io.Writer) Option {
+	return func(w *LogWriter) error {
+		w.fallback = w.JSONLinesEncoder(out).sink()
+		return nil
+	}
+}
+
+// WithBackend makes the writer deliver entries to backend instead of the one
+// selected by the connection string. Entries are always encoded as line
+// protocol for custom backends.
+func WithBackend(backend Backend) Option {
+	return func(w *LogWriter) error {
+		if backend == nil {
+			return errors.New("nil backend")
+		}
+		w.backend = backend
+		return nil
+	}
+}
+
+// WithHeaders attaches header to every write request sent over HTTP, for
+// example to pass an authenticating reverse proxy or to propagate tracing
+// headers.
+func WithHeaders(header http.Header) Option {
+	return func(w *LogWriter) error {
+		w.transport.header = header.Clone()
+		return nil
+	}
+}
+
+// WithConnectionRefresh drops the pooled HTTP connections every interval,
+// and after maxFailures write requests in a row failed or got a server
+// error, so that the server address is resolved and dialed again. This
+// keeps long-lived writers from sticking to a server that moved behind a
+// load balancer. Either trigger is disabled by passing zero.
+func WithConnectionRefresh(interval time.Duration, maxFailures int) Option {
+	return func(w *LogWriter) error {
+		if interval < 0 || maxFailures < 0 {
+			return errors.New("invalid connection refresh settings")
+		}
+		w.transport.refreshInterval = interval
+		w.transport.refreshFailures = maxFailures
+		return nil
+	}
+}
+
+// WithTransportConfig tunes the connections to the server, such as how
+// many are kept idle and for how long, to suit high-throughput writers. Dial
+// settings also apply to the Telegraf socket backends.
+func WithTransportConfig(config TransportConfig) Option {
+	return func(w *LogWriter) error {
+		if err := config.validate(); err != nil {
+			return err
+		}
+		w.transport.config = config
+		return nil
+	}
+}
+
+// WithProxy sends HTTP writes through the proxy at proxyURL, such as
+// http://proxy.internal:3128, instead of the one set by the HTTP_PROXY,
+// HTTPS_PROXY and NO_PROXY environment variables.
+func WithProxy(proxyURL string) Option {
+	return func(w *LogWriter) error {
+		u, err := parseProxyURL(proxyURL)
+		if err != nil {
+			return err
+		}
+		w.transport.proxy = u
+		return nil
+	}
+}
+
+// WithTimestampFormat sets how the timestamp field, which repeats the time
+// of the point as a field, is rendered.
+func WithTimestampFormat(format TimestampFormat) Option {
+	return func(w *LogWriter) error {
+		if format == nil {
+			return errors.New("nil timestamp format")
+		}
+		w.timestampFormat = format
+		return nil
+	}
+}
+
+// WithoutTimestampField leaves out the timestamp field, which only repeats
+// the time every point already carries, to save storage at high volumes.
+func WithoutTimestampField() Option {
+	return func(w *LogWriter) error {
+		delete(w.fields, "timestamp")
+		return nil
+	}
+}
+
+// WithHostTag writes the host name only under the tag name, which must be
+// "host" or "hostname", instead of under both.
+func WithHostTag(name string) Option {
+	return func(w *LogWriter) error {
+		var other string
+		switch name {
+		case "host":
+			other = "hostname"
+		case "hostname":
+			other = "host"
+		default:
+			return errors.New("invalid host tag " + strconv.Quote(name))
+		}
+		for _, tags := range w.tags {
+			delete(tags, other)
+		}
+		return nil
+	}
+}
+
+// WithFacility sets the syslog facility entries are written with, such as
+// "daemon"/3 or "local0"/16, instead of "user"/1.
+func WithFacility(name string, code int) Option {
+	return func(w *LogWriter) error {
+		if name == "" || code < 0 || code > 23 {
+			return errors.New("invalid facility")
+		}
+		for _, tags := range w.tags {
+			tags["facility"] = name
+		}
+		w.fields["facility_code"] = code
+		return nil
+	}
+}
+
+// WithRFC5424 writes entry fields as RFC 5424 structured data with the
+// given SD-ID, in the layout Telegraf's syslog input uses, so entries are
+// interchangeable with syslog messages ingested by Telegraf. A "msgid"
+// entry field is written as the message's MSGID.
+func WithRFC5424(sdID string) Option {
+	return func(w *LogWriter) error {
+		if err := checkSDID(sdID); err != nil {
+			return err
+		}
+		w.sdID = sdID
+		return nil
+	}
+}
+
+// telegrafSeverity holds the severity keywords of Telegraf's syslog input
+// where they differ from the default ones.
+var telegrafSeverity = map[logging.Level]string{
+	logging.WarnLevel: "warning",
+}
+
+// WithMeasurement sets the measurement entries are written to, which is
+// DefaultMeasurement by default.
+func WithMeasurement(measurement string) Option {
+	return func(w *LogWriter) error {
+		if measurement == "" {
+			return errors.New("empty measurement")
+		}
+		w.measurement = measurement
+		return nil
+	}
+}
+
+// WithTelegrafSyslogSchema makes tags and fields match the schema of
+// Telegraf's syslog input in names, types and severity keywords, so
+// dashboards built for it work unmodified. Combine it with WithRFC5424 to
+// also store entry fields as Telegraf stores structured data.
+//
+// The schema is applied once all options ran, and options changing the
+// measurement, the timestamp format or the timestamp field take precedence
+// over it whatever their order.
+func WithTelegrafSyslogSchema() Option {
+	return func(w *LogWriter) error {
+		w.telegrafSchema = true
+		return nil
+	}
+}
+
+// resolveSchema applies the schema chosen by the options once they all ran,
+// so that their order doesn't matter.
+func (w *LogWriter) resolveSchema() {
+	if w.telegrafSchema {
+		for level, keyword := range telegrafSeverity {
+			w.tags[level]["severity"] = keyword
+		}
+	}
+	if w.timestampFormat == nil {
+		w.timestampFormat = TimestampRFC3339
+		if w.telegrafSchema {
+			// Telegraf stores the message time as unix nanoseconds
+			w.timestampFormat = TimestampUnixNano
+		}
+	}
+}
+
+// WithTraceSeverity gives the trace level its own severity keyword and code,
+// such as "trace"/8, instead of sharing "debug"/7 with the debug level, so
+// trace entries can be told apart and filtered out server-side.
+func WithTraceSeverity(keyword string, code int) Option {
+	return func(w *LogWriter) error {
+		if keyword == "" || code < 0 {
+			return errors.New("invalid trace severity")
+		}
+		w.tags[logging.TraceLevel]["severity"] = keyword
+		w.severityCodes[logging.TraceLevel] = code
+		return nil
+	}
+}
+
+// WithLevelTag additionally writes the go-logging name of the level, such
+// as "warning", as the tag key, for queries written against level names
+// rather than syslog severities.
+func WithLevelTag(key string) Option {
+	return func(w *LogWriter) error {
+		if key == "" {
+			return errors.New("empty level tag key")
+		}
+		for level, tags := range w.tags {
+			tags[key] = levelNames[level]
+		}
+		return nil
+	}
+}
+
+// reservedFieldKeys are the keys of the fields the writer fills in itself.
+var reservedFieldKeys = map[string]bool{
+	"message":       true,
+	"template":      true,
+	"msgid":         true,
+	"procid":        true,
+	"version":       true,
+	"facility_code": true,
+	"severity_code": true,
+	"timestamp":     true,
+	"chunk_id":      true,
+	"chunk_index":   true,
+	"chunk_count":   true,
+}
+
+// WithLevelField is like WithLevelTag, but writes the level name as a field.
+// The key must not be one of a field the writer writes itself, such as
+// "message", nor the key of a tag or of an RFC 5424 structured data field.
+func WithLevelField(key string) Option {
+	return func(w *LogWriter) error {
+		if key == "" {
+			return errors.New("empty level field key")
+		}
+		if reservedFieldKeys[key] || strings.HasPrefix(key, "fields.") {
+			return errors.New("reserved level field key " + strconv.Quote(key))
+		}
+		w.levelField = key
+		w.fields[key] = ""
+		return nil
+	}
+}
+
+// checkLevelField checks that the level field collides with neither a tag
+// nor the structured data fields, which depend on other options and can
+// only be checked once they all ran.
+func (w *LogWriter) checkLevelField() error {
+	key := w.levelField
+	if key == "" {
+		return nil
+	}
+	if _, ok := w.tags[logging.InfoLevel][key]; ok || key == "multiline" {
+		return errors.New("level field key " + strconv.Quote(key) + " is a tag key")
+	}
+	if w.sdID != "" && (key == w.sdID || strings.HasPrefix(key, w.sdID+"_")) {
+		return errors.New("level field key " + strconv.Quote(key) + " collides with structured data")
+	}
+	return nil
+}
+
+// WithMultilinePolicy sets how messages containing line breaks are written,
+// so stack-trace-like messages don't break line-oriented tooling.
+func WithMultilinePolicy(policy MultilinePolicy) Option {
+	return func(w *LogWriter) error {
+		if policy < MultilineKeep || policy > MultilineTag {
+			return errors.New("invalid multiline policy")
+		}
+		w.multiline = policy
+		return nil
+	}
+}
+
+// WithMultilineSeparator sets the separator replacing line breaks under
+// MultilineReplace.
+func WithMultilineSeparator(separator string) Option {
+	return func(w *LogWriter) error {
+		w.multilineSeparator = separator
+		return nil
+	}
+}
+
+// WithBytesEncoding sets how []byte field values are written: as text when
+// they are valid UTF-8, and in encoding otherwise. Values longer than
+// maxBytes bytes are cut short before encoding and marked with "...";
+// zero means no limit.
+func WithBytesEncoding(encoding BytesEncoding, maxBytes int) Option {
+	return func(w *LogWriter) error {
+		if encoding < BytesBase64 || encoding > BytesHex || maxBytes < 0 {
+			return errors.New("invalid bytes encoding")
+		}
+		w.bytesEncoding = encoding
+		w.maxBytes = maxBytes
+		return nil
+	}
+}
+
+// WithTimeFieldFormat sets how time.Time field values are written. They are
+// RFC 3339 strings with nanoseconds by default.
+func WithTimeFieldFormat(format TimestampFormat) Option {
+	return func(w *LogWriter) error {
+		if format == nil {
+			return errors.New("nil time field format")
+		}
+		w.timeFieldFormat = format
+		return nil
+	}
+}
+
+// WithDurationEncoding sets how time.Duration field values are written.
+// They are a float of seconds by default.
+func WithDurationEncoding(encoding DurationEncoding) Option {
+	return func(w *LogWriter) error {
+		if encoding < DurationSeconds || encoding > DurationNanoseconds {
+			return errors.New("invalid duration encoding")
+		}
+		w.durationEncoding = encoding
+		return nil
+	}
+}
+
+// WithFieldTypeNames adds a companion <key>_type field holding the concrete
+// type of every field value written through its Error or String method.
+func WithFieldTypeNames() Option {
+	return func(w *LogWriter) error {
+		w.fieldTypeNames = true
+		return nil
+	}
+}
+
+// WithInvalidValuePolicy sets what happens to nil, NaN and infinite field
+// values, which line protocol can't represent. They are dropped by default,
+// so they never fail the entry or its batch.
+func WithInvalidValuePolicy(policy InvalidValuePolicy) Option {
+	return func(w *LogWriter) error {
+		if policy < InvalidValueDrop || policy > InvalidValueError {
+			return errors.New("unknown invalid value policy")
+		}
+		w.invalidValues = policy
+		return nil
+	}
+}
+
+// WithAsyncHooks runs OnLevel hooks on a separate goroutine through a queue
+// of queueSize calls, so slow hooks don't hold up logging. Calls that don't
+// fit in the queue are dropped and reported to the error handler. Logging
+// at the fatal level waits a few seconds at most for queued calls to run
+// before exiting.
+func WithAsyncHooks(queueSize int) Option {
+	return func(w *LogWriter) error {
+		if queueSize <= 0 {
+			return errors.New("invalid hook queue size")
+		}
+		w.hookQueue = make(chan hookCall, queueSize)
+		return nil
+	}
+}
+
+// WithErrorRateAlert calls alert when more than threshold entries at error
+// level or above are logged within window, with the number counted and the
+// entry that crossed the threshold. The count starts over after each alert.
+// alert runs as an OnLevel hook, so it follows WithAsyncHooks.
+func WithErrorRateAlert(threshold int, window time.Duration, alert func(count int, last Entry)) Option {
+	return func(w *LogWriter) error {
+		if threshold <= 0 {
+			return errors.New("invalid error alert threshold")
+		}
+		if window <= 0 {
+			return errors.New("invalid error alert window")
+		}
+		if alert == nil {
+			return errors.New("nil error alert callback")
+		}
+		w.errorAlert = &errorRateAlert{threshold: threshold, window: window, alert: alert}
+		return nil
+	}
+}
+
+// WithLevelMetrics writes the number of entries logged per level to
+// measurement every interval, so log volume can be charted without counting
+// raw log points. Counts carry the same tags as the entries they count. If
+// nameField is not empty, entries are also counted per logger name, taken
+// from that string field and written as the logger tag.
+func WithLevelMetrics(measurement string, interval time.Duration, nameField string) Option {
+	return func(w *LogWriter) error {
+		if measurement == "" {
+			return errors.New("empty level metrics measurement")
+		}
+		if interval <= 0 {
+			return errors.New("invalid level metrics interval")
+		}
+		w.levelMetrics = &levelMetrics{
+			measurement: measurement,
+			interval:    interval,
+			nameField:   nameField,
+			counts:      map[levelCountKey]int64{},
+			stopped:     make(chan struct{}),
+		}
+		return nil
+	}
+}
+
+// WithHeartbeat writes a point to the heartbeat measurement when the writer
+// is created and then every interval until it is closed. Heartbeats carry
+// the tags shared by every entry, such as the appname and host, the pid,
+// and the uptime, which is the seconds since the writer was created rather
+// than since the process started. They pause along with entries while the
+// server rate-limits writes. Unlike entries, heartbeats keep coming while
+// nothing is logged, so alerts on their absence tell a quiet service from
+// one that is down.
+func WithHeartbeat(interval time.Duration) Option {
+	return func(w *LogWriter) error {
+		if interval <= 0 {
+			return errors.New("invalid heartbeat interval")
+		}
+		w.heartbeat = newHeartbeat(interval)
+		return nil
+	}
+}
+
+// WithClientBatching leaves buffering to the batching package of the
+// influxdb3 client instead of the writer's own buffer, holding up to the
+// buffer limit of points. Points only pile up while writes are paused by
+// the server; entries logged once the limit is reached are dropped and
+// counted in Stats.BufferFull. It requires writing points through the
+// influxdb3 client. Entries are turned into points as they are logged, so
+// entries that can't be written aren't passed to the dead-letter or
+// fallback sinks, and the batch size options don't apply.
+func WithClientBatching() Option {
+	return func(w *LogWriter) error {
+		w.clientBatching = true
+		return nil
+	}
+}
+
+// WithLevelFlushInterval flushes the buffer within interval of an entry at
+// level or above being logged, so severe entries show up quickly even when
+// the flush interval is long. WithLevelImmediateFlush writes them before
+// the call logging them returns instead.
+func WithLevelFlushInterval(level logging.Level, interval time.Duration) Option {
+	return func(w *LogWriter) error {
+		if interval <= 0 {
+			return errors.New("invalid level flush interval")
+		}
+		return w.setUrgentLevel(level, interval)
+	}
+}
+
+// WithLevelImmediateFlush flushes the buffer before a call logging an entry
+// at level or above returns, so severe entries are never held back by the
+// flush interval.
+func WithLevelImmediateFlush(level logging.Level) Option {
+	return func(w *LogWriter) error {
+		return w.setUrgentLevel(level, 0)
+	}
+}
+
+// setUrgentLevel makes entries at level or above flushed within interval,
+// or right away if it is zero.
+func (w *LogWriter) setUrgentLevel(level logging.Level, interval time.Duration) error {
+	if _, ok := levelRank[level]; !ok {
+		return errors.New("invalid level")
+	}
+	w.urgentLevel = level
+	w.urgentInterval = interval
+	w.urgentFlush = make(chan struct{}, 1)
+	return nil
+}
+
+// WithBufferWatermarks calls notify with over set once the buffer holds
+// high entries or more, and with over unset once it is back down to low
+// entries or fewer, so that verbose logging can be throttled before entries
+// are dropped. notify runs on its own goroutine, in the order of crossings.
+func WithBufferWatermarks(high, low int, notify func(over bool, length int)) Option {
+	return func(w *LogWriter) error {
+		if low < 0 || high <= low {
+			return errors.New("invalid buffer watermarks")
+		}
+		if notify == nil {
+			return errors.New("nil watermark notification")
+		}
+		w.watermarkHigh = high
+		w.watermarkLow = low
+		w.watermarkNotify = notify
+		// room for a crossing and its reversal while notify is running
+		w.watermarks = make(chan watermarkEvent, 2)
+		return nil
+	}
+}
+
+// WithConsole also prints every entry to out in a human-readable form,
+// with its time, level, message and fields, so logs can be followed during
+// development without querying the database. Levels are colored with ANSI
+// escape codes if color is set.
+func WithConsole(out io.Writer, color bool) Option {
+	return func(w *LogWriter) error {
+		if out == nil {
+			return errors.New("nil console writer")
+		}
+		w.console = &console{out: out, color: color}
+		return nil
+	}
+}
+
+// WithConsoleFallbackFromEnv turns the console tee on or off from the
+// ConsoleEnv environment variable, so the same binary can print its logs
+// in development and stay quiet in production. A level such as debug turns
+// the console on for that level and above, even if less severe than the
+// level written to InfluxDB. The console prints to standard output, in
+// color unless NO_COLOR is set. It overrides an earlier WithConsole.
+func WithConsoleFallbackFromEnv() Option {
+	return func(w *LogWriter) error {
+		enabled, level, levelSet, err := parseConsoleEnv(os.Getenv(ConsoleEnv))
+		if err != nil {
+			return err
+		}
+		if !enabled {
+			w.console = nil
+			return nil
+		}
+		_, noColor := os.LookupEnv("NO_COLOR")
+		w.console = &console{out: os.Stdout, color: !noColor, level: level, levelSet: levelSet}
+		return nil
+	}
+}
+
+// WithEnricher adds the fields returned by enricher to every entry written,
+// calling it for each. Fields of the entry take precedence over enriched
+// ones, and among enrichers the first one added wins.
+func WithEnricher(enricher Enricher) Option {
+	return func(w *LogWriter) error {
+		if enricher == nil {
+			return errors.New("nil enricher")
+		}
+		w.enrichers = append(w.enrichers, enricher)
+		return nil
+	}
+}
+
+// WithCachedEnricher is like WithEnricher, but calls enricher only once,
+// with an empty entry, when the writer is created. It suits fields that
+// don't change over the life of the process, such as BuildInfoEnricher.
+func WithCachedEnricher(enricher Enricher) Option {
+	return func(w *LogWriter) error {
+		if enricher == nil {
+			return errors.New("nil enricher")
+		}
+		w.cachedEnrichers = append(w.cachedEnrichers, enricher)
+		return nil
+	}
+}
+
+// WithUTF8Replacement sets what replaces invalid UTF-8 in messages, fields
+// and tags, which would otherwise get the entry rejected. It is U+FFFD by
+// default, and may be empty to remove invalid sequences.
+func WithUTF8Replacement(replacement string) Option {
+	return func(w *LogWriter) error {
+		if !utf8.ValidString(replacement) {
+			return errors.New("invalid UTF-8 in replacement")
+		}
+		w.utf8Replacement = replacement
+		return nil
+	}
+}
+
+// WithMaxMessageLength limits messages to limit bytes, handling longer ones
+// according to policy. Messages are only ever cut between characters.
+func WithMaxMessageLength(limit int, policy OversizePolicy) Option {
+	return func(w *LogWriter) error {
+		if limit <= 0 {
+			return errors.New("invalid message length limit")
+		}
+		if policy < OversizeTruncate || policy > OversizeChunk {
+			return errors.New("unknown oversize policy")
+		}
+		w.maxMessage = limit
+		w.oversize = policy
+		return nil
+	}
+}
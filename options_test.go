@@ -0,0 +1,173 @@
+package influxlogger
+
+import (
+	"maps"
+	"testing"
+
+	"github.com/hadi77ir/go-logging"
+)
+
+func TestWithHostTag(t *testing.T) {
+	tests := []struct {
+		name    string
+		without string
+	}{
+		{"host", "hostname"},
+		{"hostname", "host"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := writtenLine(t, logging.InfoLevel, "hello", nil, WithHostTag(tt.name))
+			if got := l.tags[tt.name]; got != "localhost" {
+				t.Errorf("got %s %q, want localhost", tt.name, got)
+			}
+			if got, ok := l.tags[tt.without]; ok {
+				t.Errorf("got %s %q, want it left out", tt.without, got)
+			}
+		})
+	}
+	if err := optionsError(WithHostTag("node")); err == nil {
+		t.Error("expected an invalid tag name to be rejected")
+	}
+}
+
+func TestWithFacility(t *testing.T) {
+	tests := []struct {
+		name  string
+		code  int
+		valid bool
+	}{
+		{"daemon", 3, true},
+		{"local0", 16, true},
+		{"local7", 23, true},
+		{"", 3, false},
+		{"local8", 24, false},
+		{"user", -1, false},
+	}
+	for _, tt := range tests {
+		if !tt.valid {
+			if err := optionsError(WithFacility(tt.name, tt.code)); err == nil {
+				t.Errorf("expected %q/%d to be rejected", tt.name, tt.code)
+			}
+			continue
+		}
+		l := writtenLine(t, logging.ErrorLevel, "hello", nil, WithFacility(tt.name, tt.code))
+		if l.tags["facility"] != tt.name || l.fields["facility_code"] != int64(tt.code) {
+			t.Errorf("got facility %q/%v, want %q/%d", l.tags["facility"], l.fields["facility_code"], tt.name, tt.code)
+		}
+	}
+}
+
+func TestTelegrafSyslogSchema(t *testing.T) {
+	l := writtenLine(t, logging.WarnLevel, "hello", nil, WithTelegrafSyslogSchema())
+	if l.measurement != DefaultMeasurement || l.tags["severity"] != "warning" {
+		t.Errorf("got measurement %q and severity %q", l.measurement, l.tags["severity"])
+	}
+	if got, want := l.fields["timestamp"], l.time.UnixNano(); got != want {
+		t.Errorf("got timestamp %#v, want %#v", got, want)
+	}
+	tests := []struct {
+		name  string
+		other Option
+		check func(t *testing.T, l line)
+	}{
+		{"measurement", WithMeasurement("logs"), func(t *testing.T, l line) {
+			if l.measurement != "logs" {
+				t.Errorf("got measurement %q", l.measurement)
+			}
+		}},
+		{"timestamp format", WithTimestampFormat(TimestampUnix), func(t *testing.T, l line) {
+			if got, want := l.fields["timestamp"], l.time.Unix(); got != want {
+				t.Errorf("got timestamp %#v, want %#v", got, want)
+			}
+		}},
+		{"without timestamp", WithoutTimestampField(), func(t *testing.T, l line) {
+			if _, ok := l.fields["timestamp"]; ok {
+				t.Error("got a timestamp field")
+			}
+		}},
+		{"level field", WithLevelField("level"), func(t *testing.T, l line) {
+			if got, want := l.fields["timestamp"], l.time.UnixNano(); l.fields["level"] != levelNames[logging.WarnLevel] || got != want {
+				t.Errorf("got level %#v and timestamp %#v", l.fields["level"], got)
+			}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := writtenLine(t, logging.WarnLevel, "hello", nil, tt.other, WithTelegrafSyslogSchema())
+			after := writtenLine(t, logging.WarnLevel, "hello", nil, WithTelegrafSyslogSchema(), tt.other)
+			for _, l := range []line{before, after} {
+				tt.check(t, l)
+				if l.tags["severity"] != "warning" {
+					t.Errorf("got severity %q", l.tags["severity"])
+				}
+			}
+			// the timestamps differ with the time of the entries
+			delete(before.fields, "timestamp")
+			delete(after.fields, "timestamp")
+			if before.measurement != after.measurement || !maps.Equal(before.tags, after.tags) || !maps.Equal(before.fields, after.fields) {
+				t.Errorf("got %+v with the schema last, and %+v with it first", before, after)
+			}
+		})
+	}
+}
+
+func TestWithTraceSeverity(t *testing.T) {
+	tests := []struct {
+		level    logging.Level
+		severity string
+		code     int64
+	}{
+		{logging.TraceLevel, "trace", 8},
+		{logging.DebugLevel, "debug", 7},
+		{logging.InfoLevel, "info", 6},
+	}
+	for _, tt := range tests {
+		l := writtenLine(t, tt.level, "hello", nil, WithTraceSeverity("trace", 8))
+		if l.tags["severity"] != tt.severity || l.fields["severity_code"] != tt.code {
+			t.Errorf("got %q/%v at %s, want %q/%d", l.tags["severity"], l.fields["severity_code"], levelNames[tt.level], tt.severity, tt.code)
+		}
+	}
+	// the default severities of other writers are left alone
+	if l := writtenLine(t, logging.TraceLevel, "hello", nil); l.tags["severity"] != "debug" || l.fields["severity_code"] != int64(7) {
+		t.Errorf("got %q/%v", l.tags["severity"], l.fields["severity_code"])
+	}
+	for _, option := range []Option{WithTraceSeverity("", 8), WithTraceSeverity("trace", -1)} {
+		if err := optionsError(option); err == nil {
+			t.Error("expected an invalid trace severity to be rejected")
+		}
+	}
+}
+
+func TestWithLevelField(t *testing.T) {
+	for _, level := range []logging.Level{logging.DebugLevel, logging.ErrorLevel} {
+		l := writtenLine(t, level, "hello", nil, WithLevelField("level"))
+		if l.fields["level"] != levelNames[level] {
+			t.Errorf("got level %#v, want %q", l.fields["level"], levelNames[level])
+		}
+	}
+	tests := []struct {
+		name string
+		opts []Option
+	}{
+		{"empty", []Option{WithLevelField("")}},
+		{"message", []Option{WithLevelField("message")}},
+		{"template", []Option{WithLevelField("template")}},
+		{"msgid", []Option{WithLevelField("msgid")}},
+		{"timestamp", []Option{WithLevelField("timestamp")}},
+		{"chunk", []Option{WithLevelField("chunk_index")}},
+		{"entry field", []Option{WithLevelField("fields.level")}},
+		{"tag", []Option{WithLevelField("severity")}},
+		{"level tag", []Option{WithLevelField("level"), WithLevelTag("level")}},
+		{"multiline tag", []Option{WithLevelField("multiline")}},
+		{"sd-id", []Option{WithLevelField("meta"), WithRFC5424("meta")}},
+		{"sd param", []Option{WithRFC5424("meta"), WithLevelField("meta_level")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := optionsError(tt.opts...); err == nil {
+				t.Error("expected the level field key to be rejected")
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package influxlogger
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// partialWriteBody is the body InfluxDB 3 answers with when some lines of a
+// write request were rejected.
+type partialWriteBody struct {
+	Error string `json:"error"`
+	Data  []struct {
+		OriginalLine string `json:"original_line"`
+		LineNumber   int    `json:"line_number"`
+		ErrorMessage string `json:"error_message"`
+	} `json:"data"`
+}
+
+// rejectedLines returns the server's reason for every rejected line of a
+// partial write, keyed by 1-based line number, or nil if err isn't a
+// partial write.
+func rejectedLines(err error) map[int]error {
+	var writeErr *WriteError
+	if !errors.As(err, &writeErr) || len(writeErr.Body) == 0 {
+		return nil
+	}
+	var body partialWriteBody
+	if json.Unmarshal(writeErr.Body, &body) != nil || len(body.Data) == 0 {
+		return nil
+	}
+	rejected := make(map[int]error, len(body.Data))
+	for _, line := range body.Data {
+		rejected[line.LineNumber] = fmt.Errorf("line %d rejected by server: %s", line.LineNumber, line.ErrorMessage)
+	}
+	return rejected
+}
+
+// salvage handles a partial write of sent. Rejected entries are handed to
+// the dead-letter sink and the valid ones are returned to be submitted
+// again; InfluxDB deduplicates points that were already accepted. It
+// reports false if err isn't a partial write. If the rejected line numbers
+// don't match what was sent, there is no telling which entries were
+// accepted, so all of sent is treated as lost.
+func (w *LogWriter) salvage(sent []*Entry, err error) ([]*Entry, bool) {
+	rejected := rejectedLines(err)
+	if len(rejected) == 0 {
+		return nil, false
+	}
+	valid := make([]*Entry, 0, len(sent))
+	for i, e := range sent {
+		if reason, ok := rejected[i+1]; ok {
+			w.rejectEntry(e, reason)
+			continue
+		}
+		valid = append(valid, e)
+	}
+	if len(valid) == len(sent) {
+		// the line numbers don't match what was sent
+		w.drops.writeFailed.Add(uint64(len(sent)))
+		w.loseEntries(sent, err)
+		return nil, false
+	}
+	return valid, true
+}
+
+// rejectEntry hands an entry that can't be written to the dead-letter sink.
+func (w *LogWriter) rejectEntry(e *Entry, reason error) {
+	w.drops.rejected.Add(1)
+	if w.deadLetter != nil {
+		w.toSink(w.deadLetter, []*Entry{e}, reason)
+	}
+}
+
+// fallBack hands entries lost to a failed write to the fallback sink.
+// Without one, entries that ran out of time go to the dead-letter sink.
+func (w *LogWriter) fallBack(entries []*Entry, err error) {
+	if writeLost(err) {
+		w.loseEntries(entries, err)
+	}
+}
+
+// loseEntries hands entries that won't be written to the fallback sink, or
+// to the dead-letter sink if they ran out of time and there's no fallback.
+func (w *LogWriter) loseEntries(entries []*Entry, err error) {
+	sink := w.fallback
+	if sink == nil && timedOut(err) {
+		sink = w.deadLetter
+	}
+	if sink == nil {
+		return
+	}
+	w.toSink(sink, entries, err)
+}
+
+// toSink hands entries to sink one at a time. Calls are serialized across
+// the writer, so sinks don't need to be safe for concurrent use even when
+// chunks are written in parallel.
+func (w *LogWriter) toSink(sink func(e Entry, reason error), entries []*Entry, reason error) {
+	w.sinkMutex.Lock()
+	defer w.sinkMutex.Unlock()
+	for _, e := range entries {
+		sink(*e, reason)
+	}
+}
+
+// timedOut reports whether err comes from a deadline running out, either
+// the context's or the write deadline of a socket backend.
+func timedOut(err error) bool {
+	return errors.Is(err, context.DeadlineExceeded) || errors.Is(err, os.ErrDeadlineExceeded)
+}
@@ -0,0 +1,165 @@
+package influxlogger
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/hadi77ir/go-logging"
+)
+
+// partialWrite answers the first request with a partial write rejecting
+// line, and the following ones with success.
+func partialWrite(line string) func(rw http.ResponseWriter, r request, n int) {
+	return func(rw http.ResponseWriter, r request, n int) {
+		if n > 1 {
+			rw.WriteHeader(http.StatusNoContent)
+			return
+		}
+		rw.WriteHeader(http.StatusBadRequest)
+		_, _ = io.WriteString(rw, `{"error":"partial write of line protocol occurred","data":[`+
+			`{"original_line":"","line_number":`+line+`,"error_message":"invalid column type"}]}`)
+	}
+}
+
+func TestPartialWrite(t *testing.T) {
+	ctx := context.Background()
+	t.Run("salvage", func(t *testing.T) {
+		server := newTestServer(t, partialWrite("2"))
+		var rejected []string
+		writer := newTestWriter(t, "lp+"+server.URL+"/write", 10, WithDeadLetter(func(e Entry, reason error) {
+			rejected = append(rejected, e.Message)
+		}))
+		writeMessages(t, writer, "first", "second", "third")
+		if err := writer.Flush(ctx); err != nil {
+			t.Fatal(err)
+		}
+		requests := server.received()
+		if len(requests) != 2 {
+			t.Fatalf("got %d requests, want 2", len(requests))
+		}
+		if got := messages(t, requests[1].body); !slices.Equal(got, []string{"first", "third"}) {
+			t.Errorf("resubmitted %q, want the valid entries", got)
+		}
+		if !slices.Equal(rejected, []string{"second"}) {
+			t.Errorf("dead-lettered %q, want the rejected entry", rejected)
+		}
+		if stats := writer.Stats(); stats.Rejected != 1 || stats.WriteFailed != 0 {
+			t.Errorf("got %+v", stats)
+		}
+	})
+	t.Run("rate-limited resubmit", func(t *testing.T) {
+		rejectSecond := partialWrite("2")
+		server := newTestServer(t, func(rw http.ResponseWriter, r request, n int) {
+			if n == 2 {
+				rw.Header().Set("Retry-After", "0")
+				rw.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			rejectSecond(rw, r, n)
+		})
+		var rejected []string
+		writer := newTestWriter(t, "lp+"+server.URL+"/write", 10, WithDeadLetter(func(e Entry, reason error) {
+			rejected = append(rejected, e.Message)
+		}))
+		writeMessages(t, writer, "first", "second", "third")
+		for range 2 {
+			if err := writer.Flush(ctx); err != nil {
+				t.Fatal(err)
+			}
+		}
+		requests := server.received()
+		if len(requests) != 3 {
+			t.Fatalf("got %d requests, want 3", len(requests))
+		}
+		if got := messages(t, requests[2].body); !slices.Equal(got, []string{"first", "third"}) {
+			t.Errorf("retried %q, want the valid entries", got)
+		}
+		if !slices.Equal(rejected, []string{"second"}) {
+			t.Errorf("dead-lettered %q, want the rejected entry once", rejected)
+		}
+		if stats := writer.Stats(); stats.Rejected != 1 || stats.RateLimited != 0 {
+			t.Errorf("got %+v", stats)
+		}
+	})
+	t.Run("unmatched lines", func(t *testing.T) {
+		server := newTestServer(t, partialWrite("7"))
+		var lost []string
+		writer := newTestWriter(t, "lp+"+server.URL+"/write", 10, WithFallback(func(e Entry, reason error) {
+			lost = append(lost, e.Message)
+		}))
+		writeMessages(t, writer, "first", "second", "third")
+		if err := writer.Flush(ctx); err == nil {
+			t.Fatal("expected the partial write to fail the flush")
+		}
+		if n := len(server.received()); n != 1 {
+			t.Errorf("got %d requests, want 1", n)
+		}
+		if !slices.Equal(lost, []string{"first", "second", "third"}) {
+			t.Errorf("fell back %q, want every entry", lost)
+		}
+		if stats := writer.Stats(); stats.WriteFailed != 3 {
+			t.Errorf("got %+v", stats)
+		}
+	})
+}
+
+func TestEntryDeadline(t *testing.T) {
+	tests := []struct {
+		name       string
+		deadline   time.Duration
+		delay      time.Duration
+		fallback   bool
+		timedOut   bool
+		fallenBack int
+		deadLetter int
+	}{
+		{"fallback", 50 * time.Millisecond, 500 * time.Millisecond, true, true, 1, 0},
+		{"dead letter without fallback", 50 * time.Millisecond, 500 * time.Millisecond, false, true, 0, 1},
+		{"within the deadline", time.Second, 0, true, false, 0, 0},
+		{"no deadline", 0, 100 * time.Millisecond, true, false, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTestServer(t, func(rw http.ResponseWriter, r request, n int) {
+				time.Sleep(tt.delay)
+				rw.WriteHeader(http.StatusNoContent)
+			})
+			var fallenBack, deadLetter []string
+			opts := []Option{
+				WithEntryDeadline(tt.deadline),
+				WithDeadLetter(func(e Entry, reason error) {
+					deadLetter = append(deadLetter, e.Message)
+				}),
+			}
+			if tt.fallback {
+				opts = append(opts, WithFallback(func(e Entry, reason error) {
+					if !timedOut(reason) {
+						t.Errorf("got reason %v, want a timeout", reason)
+					}
+					fallenBack = append(fallenBack, e.Message)
+				}))
+			}
+			writer := newTestWriter(t, "lp+"+server.URL+"/write", 0, opts...)
+			start := time.Now()
+			err := writer.Write(logging.InfoLevel, []any{"hello"}, nil)
+			elapsed := time.Since(start)
+			if timedOut(err) != tt.timedOut {
+				t.Errorf("got error %v, want a timeout: %t", err, tt.timedOut)
+			}
+			if tt.timedOut && elapsed >= tt.delay {
+				t.Errorf("call took %v, want it bounded by the deadline", elapsed)
+			}
+			if len(fallenBack) != tt.fallenBack || len(deadLetter) != tt.deadLetter {
+				t.Errorf("got fallback %q and dead letter %q, want %d and %d entries",
+					fallenBack, deadLetter, tt.fallenBack, tt.deadLetter)
+			}
+		})
+	}
+	if err := optionsError(WithEntryDeadline(-time.Second)); err == nil {
+		t.Error("expected a negative deadline to be rejected")
+	}
+}
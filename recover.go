@@ -0,0 +1,73 @@
+package influxlogger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/hadi77ir/go-logging"
+)
+
+// RecoverAndLog recovers from a panic, writes it at panic level through l
+// with the stack trace in the stack field, and flushes l, so the crash is
+// recorded even if the process dies next. It must be deferred directly:
+//
+//	defer influxlogger.RecoverAndLog(logger)
+func RecoverAndLog(l *Logger) {
+	if r := recover(); r != nil {
+		l.logPanic(r, debug.Stack())
+	}
+}
+
+// RecoverLogAndRepanic is like RecoverAndLog, but panics again with the
+// recovered value once it is recorded.
+func RecoverLogAndRepanic(l *Logger) {
+	if r := recover(); r != nil {
+		l.logPanic(r, debug.Stack())
+		panic(r)
+	}
+}
+
+// RecoveryMiddleware returns middleware recovering from panics of the
+// handler it wraps, logging them like RecoverAndLog. The client is answered
+// with 500 Internal Server Error, unless repanic is set and the panic is
+// passed on to the server. http.ErrAbortHandler, which aborts a response
+// on purpose, is passed on without being logged.
+func RecoveryMiddleware(l *Logger, repanic bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			defer func() {
+				r := recover()
+				if r == nil {
+					return
+				}
+				if err, ok := r.(error); ok && errors.Is(err, http.ErrAbortHandler) {
+					panic(r)
+				}
+				l.logPanic(r, debug.Stack())
+				if repanic {
+					panic(r)
+				}
+				http.Error(rw, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			}()
+			next.ServeHTTP(rw, req)
+		})
+	}
+}
+
+// logPanic writes the recovered value r and its stack, then flushes.
+func (l *Logger) logPanic(r any, stack []byte) {
+	fields := make(logging.Fields, len(l.fields)+2)
+	maps.Copy(fields, l.fields)
+	fields["panic"] = fmt.Sprint(r)
+	fields["stack"] = string(stack)
+	// written straight to the writer, as logging at panic level would
+	// panic again
+	_ = l.writer.Write(logging.PanicLevel, []any{"recovered from panic: ", r}, fields)
+	ctx, cancel := context.WithTimeout(context.Background(), defaultHTTPTimeout)
+	defer cancel()
+	_ = l.writer.Flush(ctx)
+}
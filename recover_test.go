@@ -0,0 +1,132 @@
+package influxlogger
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hadi77ir/go-logging"
+)
+
+// newRecoveryLogger returns a buffered logger that is only flushed when
+// asked to, so lines only reach the backend once a recovery flushes them.
+func newRecoveryLogger(t *testing.T) (*Logger, *recordingBackend) {
+	t.Helper()
+	backend := &recordingBackend{}
+	writer := newTestWriter(t, "", 10, WithBackend(backend))
+	return &Logger{writer: writer, fields: logging.Fields{"service": "api"}}, backend
+}
+
+// checkPanicLine checks that lines hold the single recorded panic of value.
+func checkPanicLine(t *testing.T, lines []line, value string) {
+	t.Helper()
+	if len(lines) != 1 {
+		t.Fatalf("got %d lines, want 1", len(lines))
+	}
+	l := lines[0]
+	if got, want := l.fields["message"], "recovered from panic: "+value; got != want {
+		t.Errorf("got message %#v, want %q", got, want)
+	}
+	if l.tags["severity"] != "emerg" {
+		t.Errorf("got severity %q, want emerg", l.tags["severity"])
+	}
+	if l.fields["fields.panic"] != value || l.fields["fields.service"] != "api" {
+		t.Errorf("got fields %v", l.fields)
+	}
+	if stack, _ := l.fields["fields.stack"].(string); !strings.Contains(stack, "recover_test.go") {
+		t.Errorf("got stack %q, want the one of the panic", stack)
+	}
+}
+
+func TestRecoverAndLog(t *testing.T) {
+	tests := []struct {
+		name    string
+		recover func(l *Logger)
+		repanic bool
+	}{
+		{"recover", RecoverAndLog, false},
+		{"repanic", RecoverLogAndRepanic, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, backend := newRecoveryLogger(t)
+			var repanicked any
+			func() {
+				defer func() { repanicked = recover() }()
+				func() {
+					defer tt.recover(logger)
+					panic("boom")
+				}()
+			}()
+			if (repanicked != nil) != tt.repanic {
+				t.Errorf("got panic %v passed on, want it passed on: %t", repanicked, tt.repanic)
+			}
+			// the panic is flushed without waiting for the flush interval
+			checkPanicLine(t, backend.written(t), "boom")
+		})
+	}
+	t.Run("no panic", func(t *testing.T) {
+		logger, backend := newRecoveryLogger(t)
+		func() {
+			defer RecoverAndLog(logger)
+		}()
+		if err := logger.writer.Flush(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if lines := backend.written(t); len(lines) != 0 {
+			t.Errorf("got %d lines without a panic", len(lines))
+		}
+	})
+}
+
+func TestRecoveryMiddleware(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   any
+		repanic bool
+		// passedOn is set when the panic reaches the server, and status is
+		// the one answered otherwise
+		passedOn bool
+		status   int
+		logged   bool
+	}{
+		{"no panic", nil, false, false, http.StatusOK, false},
+		{"recovered", "boom", false, false, http.StatusInternalServerError, true},
+		{"repanic", "boom", true, true, 0, true},
+		{"abort", http.ErrAbortHandler, false, true, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, backend := newRecoveryLogger(t)
+			handler := RecoveryMiddleware(logger, tt.repanic)(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+				if tt.value != nil {
+					panic(tt.value)
+				}
+			}))
+			rec := httptest.NewRecorder()
+			var passedOn any
+			func() {
+				defer func() { passedOn = recover() }()
+				handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+			}()
+			if (passedOn != nil) != tt.passedOn {
+				t.Errorf("got panic %v passed on, want it passed on: %t", passedOn, tt.passedOn)
+			}
+			if !tt.passedOn && rec.Code != tt.status {
+				t.Errorf("got status %d, want %d", rec.Code, tt.status)
+			}
+			if !tt.logged {
+				if err := logger.writer.Flush(context.Background()); err != nil {
+					t.Fatal(err)
+				}
+				if lines := backend.written(t); len(lines) != 0 {
+					t.Errorf("got %d lines, want none", len(lines))
+				}
+				return
+			}
+			checkPanicLine(t, backend.written(t), "boom")
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package influxlogger
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// defaultRetryAfter is how long writes pause after being rate limited when
+// the server doesn't say how long to wait.
+const defaultRetryAfter = 10 * time.Second
+
+// ErrRateLimited is returned by unbuffered writes while writing is paused
+// after the server asked the client to back off.
+var ErrRateLimited = errors.New("writes paused by server rate limiting")
+
+// WriteError is returned when the server rejects a write request.
+type WriteError struct {
+	// StatusCode holds the HTTP status code of the response.
+	StatusCode int
+	// RetryAfter holds the delay requested by the Retry-After header, and
+	// RetryAfterSent whether a valid one was sent at all. A delay of zero
+	// asks to retry right away.
+	RetryAfter     time.Duration
+	RetryAfterSent bool
+	// Body holds the body of the response, up to 1 MiB.
+	Body []byte
+	Err  error
+}
+
+func (e *WriteError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *WriteError) Unwrap() error {
+	return e.Err
+}
+
+// rateLimited reports whether err asks the client to back off.
+func rateLimited(err error) (time.Duration, bool) {
+	var writeErr *WriteError
+	if !errors.As(err, &writeErr) {
+		return 0, false
+	}
+	if writeErr.StatusCode != http.StatusTooManyRequests && writeErr.StatusCode != http.StatusServiceUnavailable {
+		return 0, false
+	}
+	if !writeErr.RetryAfterSent {
+		return defaultRetryAfter, true
+	}
+	return writeErr.RetryAfter, true
+}
+
+func (w *LogWriter) paused() bool {
+	return time.Now().UnixNano() < w.pausedUntil.Load()
+}
+
+// pause stops writes for d. A pause already lasting longer is kept.
+func (w *LogWriter) pause(d time.Duration) {
+	until := time.Now().Add(d).UnixNano()
+	for {
+		current := w.pausedUntil.Load()
+		if current >= until || w.pausedUntil.CompareAndSwap(current, until) {
+			return
+		}
+	}
+}
+
+// deliverChunk writes a chunk unless writes are paused. Chunks that arrive
+// during a pause are kept for a later flush, like those rate limited by
+// writeChunk.
+func (w *LogWriter) deliverChunk(ctx context.Context, entries []*Entry) error {
+	if w.paused() {
+		return w.deferEntries(entries, ErrRateLimited)
+	}
+	return w.writeChunk(ctx, entries)
+}
+
+// backOff pauses writes for retryAfter and keeps entries, whose write was
+// rate limited with err, for a later flush.
+func (w *LogWriter) backOff(entries []*Entry, err error, retryAfter time.Duration) error {
+	w.pause(retryAfter)
+	return w.deferEntries(entries, err)
+}
+
+// deferEntries keeps entries to be put back into the buffer once the
+// current flush is done. Unbuffered writers have no place to keep them, so
+// they go to the fallback sink and err is returned instead.
+func (w *LogWriter) deferEntries(entries []*Entry, err error) error {
+	if !w.buffered() {
+		w.drops.rateLimited.Add(uint64(len(entries)))
+		if w.fallback != nil {
+			w.toSink(w.fallback, entries, err)
+		}
+		return err
+	}
+	w.requeueMutex.Lock()
+	defer w.requeueMutex.Unlock()
+	w.requeue = append(w.requeue, entries...)
+	return nil
+}
@@ -0,0 +1,144 @@
+package influxlogger
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/hadi77ir/go-logging"
+)
+
+func TestRateLimitRequeue(t *testing.T) {
+	ctx := context.Background()
+	rateLimited := func(retryAfter string) func(rw http.ResponseWriter, r request, n int) {
+		return func(rw http.ResponseWriter, r request, n int) {
+			if n > 1 {
+				rw.WriteHeader(http.StatusNoContent)
+				return
+			}
+			rw.Header().Set("Retry-After", retryAfter)
+			rw.WriteHeader(http.StatusTooManyRequests)
+		}
+	}
+	t.Run("retried", func(t *testing.T) {
+		server := newTestServer(t, rateLimited("0"))
+		writer := newTestWriter(t, "lp+"+server.URL+"/write", 10)
+		writeMessages(t, writer, "first", "second")
+		if err := writer.Flush(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if n := writer.BufferLen(); n != 2 {
+			t.Fatalf("got %d buffered entries after the rate limit, want 2", n)
+		}
+		if err := writer.Flush(ctx); err != nil {
+			t.Fatal(err)
+		}
+		requests := server.received()
+		if len(requests) != 2 {
+			t.Fatalf("got %d requests, want 2", len(requests))
+		}
+		if got := messages(t, requests[1].body); !slices.Equal(got, []string{"first", "second"}) {
+			t.Errorf("retried %q, want the requeued entries", got)
+		}
+		if n := writer.BufferLen(); n != 0 {
+			t.Errorf("got %d buffered entries, want 0", n)
+		}
+		if stats := writer.Stats(); stats != (Stats{}) {
+			t.Errorf("got %+v", stats)
+		}
+	})
+	t.Run("paused", func(t *testing.T) {
+		server := newTestServer(t, rateLimited("60"))
+		writer := newTestWriter(t, "lp+"+server.URL+"/write", 10)
+		writeMessages(t, writer, "first", "second")
+		for range 2 {
+			if err := writer.Flush(ctx); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if n := len(server.received()); n != 1 {
+			t.Errorf("got %d requests while paused, want 1", n)
+		}
+		if n := writer.BufferLen(); n != 2 {
+			t.Errorf("got %d buffered entries, want 2", n)
+		}
+	})
+}
+
+func TestRateLimited(t *testing.T) {
+	tests := []struct {
+		name  string
+		err   error
+		delay time.Duration
+		ok    bool
+	}{
+		{"nil", nil, 0, false},
+		{"other error", errors.New("connection refused"), 0, false},
+		{"bad request", &WriteError{StatusCode: http.StatusBadRequest}, 0, false},
+		{"too many requests", &WriteError{StatusCode: http.StatusTooManyRequests, RetryAfter: time.Minute, RetryAfterSent: true}, time.Minute, true},
+		{"unavailable", &WriteError{StatusCode: http.StatusServiceUnavailable, RetryAfter: time.Second, RetryAfterSent: true}, time.Second, true},
+		{"no retry-after", &WriteError{StatusCode: http.StatusTooManyRequests}, defaultRetryAfter, true},
+		{"zero retry-after", &WriteError{StatusCode: http.StatusTooManyRequests, RetryAfterSent: true}, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			delay, ok := rateLimited(tt.err)
+			if delay != tt.delay || ok != tt.ok {
+				t.Errorf("got %v, %v, want %v, %v", delay, ok, tt.delay, tt.ok)
+			}
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value string
+		delay time.Duration
+		sent  bool
+	}{
+		{"", 0, false},
+		{"0", 0, true},
+		{"120", 2 * time.Minute, true},
+		{"-1", 0, false},
+		{"soon", 0, false},
+		{now.Add(30 * time.Second).Format(http.TimeFormat), 30 * time.Second, true},
+		{now.Add(-time.Minute).Format(http.TimeFormat), 0, true},
+	}
+	for _, tt := range tests {
+		delay, sent := parseRetryAfter(tt.value, now)
+		if delay != tt.delay || sent != tt.sent {
+			t.Errorf("parseRetryAfter(%q) = %v, %v, want %v, %v", tt.value, delay, sent, tt.delay, tt.sent)
+		}
+	}
+}
+
+func TestRateLimitUnbuffered(t *testing.T) {
+	server := newTestServer(t, func(rw http.ResponseWriter, r request, n int) {
+		rw.Header().Set("Retry-After", "60")
+		rw.WriteHeader(http.StatusTooManyRequests)
+	})
+	var lost []string
+	writer := newTestWriter(t, "lp+"+server.URL+"/write", 0, WithFallback(func(e Entry, reason error) {
+		lost = append(lost, e.Message)
+	}))
+	var writeErr *WriteError
+	if err := writer.Write(logging.InfoLevel, []any{"first"}, nil); !errors.As(err, &writeErr) {
+		t.Fatalf("got %v, want the rate limit", err)
+	}
+	if err := writer.Write(logging.InfoLevel, []any{"second"}, nil); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("got %v while paused, want ErrRateLimited", err)
+	}
+	if n := len(server.received()); n != 1 {
+		t.Errorf("got %d requests, want 1", n)
+	}
+	if !slices.Equal(lost, []string{"first", "second"}) {
+		t.Errorf("fell back %q, want both entries", lost)
+	}
+	if stats := writer.Stats(); stats.RateLimited != 2 {
+		t.Errorf("got %+v", stats)
+	}
+}
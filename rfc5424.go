@@ -0,0 +1,72 @@
+package influxlogger
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// maxSDNameLength is the longest SD-NAME RFC 5424 allows.
+const maxSDNameLength = 32
+
+// eachStructuredDataField calls fn for the fields of e the way Telegraf's
+// syslog input stores RFC 5424 structured data: a boolean field named after
+// the SD-ID, and a string field named <SD-ID>_<PARAM-NAME> per parameter.
+// A "msgid" entry field becomes the MSGID instead.
+func (w *LogWriter) eachStructuredDataField(e *Entry, fn func(key string, value any) error) error {
+	params := 0
+	for key, value := range e.Fields {
+		var err error
+		if key == "msgid" {
+			err = fn("msgid", sdName(fmt.Sprint(fieldValue(w.convertField(value)))))
+		} else {
+			params++
+			err = w.emitField(w.sdKeys.get(key), value, func(key string, value any) error {
+				return fn(key, fmt.Sprint(fieldValue(value)))
+			})
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if params == 0 {
+		return nil
+	}
+	return fn(w.sdID, true)
+}
+
+// sdName turns s into a valid SD-NAME: at most 32 printable US-ASCII
+// characters other than '=', ' ', ']' and '"'. Other characters are
+// replaced with '_'.
+func sdName(s string) string {
+	if s == "" {
+		return "_"
+	}
+	var b strings.Builder
+	for i := 0; i < len(s) && b.Len() < maxSDNameLength; i++ {
+		c := s[i]
+		if !validSDNameChar(c) {
+			c = '_'
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+func validSDNameChar(c byte) bool {
+	return c > ' ' && c < 0x7f && c != '=' && c != ']' && c != '"'
+}
+
+// checkSDID checks that id is a valid SD-ID, such as "meta" or
+// "fields@32473".
+func checkSDID(id string) error {
+	if id == "" || len(id) > maxSDNameLength {
+		return errors.New("invalid structured data id length")
+	}
+	for i := 0; i < len(id); i++ {
+		if !validSDNameChar(id[i]) {
+			return fmt.Errorf("invalid character %q in structured data id", id[i])
+		}
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package influxlogger
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hadi77ir/go-logging"
+)
+
+func TestSDName(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"", "_"},
+		{"user", "user"},
+		{"user id", "user_id"},
+		{`a=b]"c`, "a_b__c"},
+		{"héllo", "h__llo"},
+		{strings.Repeat("x", 40), strings.Repeat("x", maxSDNameLength)},
+	}
+	for _, tt := range tests {
+		if got := sdName(tt.in); got != tt.want {
+			t.Errorf("sdName(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestCheckSDID(t *testing.T) {
+	tests := []struct {
+		id    string
+		valid bool
+	}{
+		{"meta", true},
+		{"fields@32473", true},
+		{"", false},
+		{"with space", false},
+		{`quote"`, false},
+		{strings.Repeat("x", 33), false},
+	}
+	for _, tt := range tests {
+		if err := checkSDID(tt.id); (err == nil) != tt.valid {
+			t.Errorf("checkSDID(%q) = %v, want valid %v", tt.id, err, tt.valid)
+		}
+	}
+}
+
+func TestStructuredData(t *testing.T) {
+	l := writtenLine(t, logging.InfoLevel, "hello", logging.Fields{
+		"user id": 42,
+		"path":    "/login",
+		"msgid":   "login ok",
+	}, WithRFC5424("meta"))
+	want := map[string]any{
+		"meta":         true,
+		"meta_user_id": "42",
+		"meta_path":    "/login",
+		"msgid":        "login_ok",
+	}
+	for key, value := range want {
+		if l.fields[key] != value {
+			t.Errorf("got %s %#v, want %#v", key, l.fields[key], value)
+		}
+	}
+	for key := range l.fields {
+		if strings.HasPrefix(key, "fields.") {
+			t.Errorf("got field %s outside of the structured data", key)
+		}
+	}
+	// without parameters there is no structured data element
+	l = writtenLine(t, logging.InfoLevel, "hello", logging.Fields{"msgid": "start"}, WithRFC5424("meta"))
+	if _, ok := l.fields["meta"]; ok || l.fields["msgid"] != "start" {
+		t.Errorf("got fields %v", l.fields)
+	}
+	if err := optionsError(WithRFC5424("bad id")); err == nil {
+		t.Error("expected an invalid SD-ID to be rejected")
+	}
+}
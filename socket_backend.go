@@ -0,0 +1,112 @@
+package influxlogger
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// closeCheckTimeout bounds the wait of the check for a connection closed by
+// the peer, made before every write.
+const closeCheckTimeout = time.Millisecond
+
+// socketBackend writes line protocol to a stream socket, such as the one of
+// Telegraf's socket_listener input, which takes care of batching, retries
+// and routing.
+type socketBackend struct {
+	network string
+	address string
+	dialer  net.Dialer
+	mutex   sync.Mutex
+	conn    net.Conn
+}
+
+// newSocketBackend creates a socket backend from a connection string like
+//
+//	telegraf+tcp://localhost:8094
+//	telegraf+unix:///var/run/telegraf.sock
+func newSocketBackend(connection string, options *transportOptions) (*socketBackend, error) {
+	u, err := url.Parse(connection)
+	if err != nil {
+		return nil, err
+	}
+	b := &socketBackend{
+		network: strings.TrimPrefix(u.Scheme, "telegraf+"),
+		dialer:  *options.dialer(),
+	}
+	switch b.network {
+	case "tcp":
+		b.address = u.Host
+	case "unix":
+		b.address = u.Path
+	default:
+		return nil, errors.New("unsupported network " + b.network)
+	}
+	if b.address == "" {
+		return nil, errors.New("missing socket address")
+	}
+	return b, nil
+}
+
+func (b *socketBackend) WriteLineProtocol(ctx context.Context, lines []byte) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.conn != nil && peerClosed(b.conn) {
+		_ = b.conn.Close()
+		b.conn = nil
+	}
+	for retried := false; ; retried = true {
+		if b.conn == nil {
+			conn, err := b.dialer.DialContext(ctx, b.network, b.address)
+			if err != nil {
+				return err
+			}
+			b.conn = conn
+		}
+		deadline, _ := ctx.Deadline()
+		if err := b.conn.SetWriteDeadline(deadline); err != nil {
+			return err
+		}
+		n, err := b.conn.Write(lines)
+		if err == nil {
+			return nil
+		}
+		_ = b.conn.Close()
+		b.conn = nil
+		// a connection that broke since it was checked fails before
+		// anything is sent; retry those once on a new connection, but
+		// never resend a partially written batch
+		if retried || n > 0 {
+			return err
+		}
+	}
+}
+
+// peerClosed reports whether the peer closed conn. A write to such a
+// connection usually succeeds, and its lines are lost when the peer answers
+// with a reset. The listener never sends anything, so a read that doesn't
+// time out means the connection was closed or broke.
+func peerClosed(conn net.Conn) bool {
+	if err := conn.SetReadDeadline(time.Now().Add(closeCheckTimeout)); err != nil {
+		return true
+	}
+	var buf [1]byte
+	_, err := conn.Read(buf[:])
+	return !errors.Is(err, os.ErrDeadlineExceeded)
+}
+
+func (b *socketBackend) Close() error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.conn == nil {
+		return nil
+	}
+	err := b.conn.Close()
+	b.conn = nil
+	return err
+}
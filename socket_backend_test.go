@@ -0,0 +1,110 @@
+package influxlogger
+
+import (
+	"bufio"
+	"net"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+// lineListener accepts connections on a stream socket and collects the
+// messages of the lines received over them.
+type lineListener struct {
+	net.Listener
+	lines chan string
+	conns chan net.Conn
+}
+
+func listenLines(t *testing.T, network, address string) *lineListener {
+	t.Helper()
+	listener, err := net.Listen(network, address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := &lineListener{Listener: listener, lines: make(chan string, 10), conns: make(chan net.Conn, 10)}
+	t.Cleanup(func() { _ = l.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			l.conns <- conn
+			go func() {
+				scanner := bufio.NewScanner(conn)
+				for scanner.Scan() {
+					l.lines <- scanner.Text()
+				}
+			}()
+		}
+	}()
+	return l
+}
+
+// next returns the message of the next line received.
+func (l *lineListener) next(t *testing.T) string {
+	t.Helper()
+	select {
+	case line := <-l.lines:
+		return messages(t, line+"\n")[0]
+	case <-time.After(5 * time.Second):
+		t.Fatal("no line received")
+		return ""
+	}
+}
+
+// restart closes the listener and the connections it accepted, and
+// listens again at the same address.
+func (l *lineListener) restart(t *testing.T) *lineListener {
+	t.Helper()
+	network, address := l.Addr().Network(), l.Addr().String()
+	_ = l.Close()
+	for len(l.conns) > 0 {
+		_ = (<-l.conns).Close()
+	}
+	return listenLines(t, network, address)
+}
+
+func TestSocketBackend(t *testing.T) {
+	tests := []struct {
+		network string
+		address func(t *testing.T) string
+	}{
+		{"tcp", func(t *testing.T) string { return "127.0.0.1:0" }},
+		{"unix", func(t *testing.T) string { return filepath.Join(t.TempDir(), "telegraf.sock") }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.network, func(t *testing.T) {
+			listener := listenLines(t, tt.network, tt.address(t))
+			connection := "telegraf+tcp://" + listener.Addr().String()
+			if tt.network == "unix" {
+				connection = "telegraf+unix://" + listener.Addr().String()
+			}
+			writer := newTestWriter(t, connection, 0)
+			writeMessages(t, writer, "first", "second")
+			if got := []string{listener.next(t), listener.next(t)}; !slices.Equal(got, []string{"first", "second"}) {
+				t.Errorf("got %q", got)
+			}
+			// the first write after the listener went away must not be lost
+			listener = listener.restart(t)
+			writeMessages(t, writer, "third")
+			if got := listener.next(t); got != "third" {
+				t.Errorf("got %q after a restart, want third", got)
+			}
+		})
+	}
+}
+
+func TestSocketBackendConnection(t *testing.T) {
+	for _, connection := range []string{
+		"telegraf+udp://localhost:8094",
+		"telegraf+tcp://",
+		"telegraf+unix://",
+	} {
+		if _, err := newSocketBackend(connection, &transportOptions{}); err == nil {
+			t.Errorf("expected %s to be rejected", connection)
+		}
+	}
+}
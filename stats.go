@@ -0,0 +1,152 @@
+package influxlogger
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// Stats counts the entries a writer dropped, by reason.
+type Stats struct {
+	// BufferFull counts entries that didn't fit in the buffer, because it
+	// was full and couldn't be flushed
+	BufferFull uint64
+	// RateLimited counts entries dropped while the server was limiting
+	// writes, either unbuffered or with no room to keep them for later
+	RateLimited uint64
+	// Rejected counts entries that were malformed or rejected by the server
+	Rejected uint64
+	// WriteFailed counts entries lost to failed writes
+	WriteFailed uint64
+}
+
+type dropCounters struct {
+	bufferFull  atomic.Uint64
+	rateLimited atomic.Uint64
+	rejected    atomic.Uint64
+	writeFailed atomic.Uint64
+}
+
+// Stats returns the number of entries dropped since the writer was created.
+func (w *LogWriter) Stats() Stats {
+	return Stats{
+		BufferFull:  w.drops.bufferFull.Load(),
+		RateLimited: w.drops.rateLimited.Load(),
+		Rejected:    w.drops.rejected.Load(),
+		WriteFailed: w.drops.writeFailed.Load(),
+	}
+}
+
+// LastError returns the error of the most recent write that failed, or nil
+// if none has. It isn't cleared by later successful writes; compare with
+// LastSuccess to tell whether writes are failing right now.
+func (w *LogWriter) LastError() error {
+	w.lastMutex.Lock()
+	defer w.lastMutex.Unlock()
+	return w.lastError
+}
+
+// LastSuccess returns the time of the most recent successful write, or the
+// zero time if none has succeeded yet.
+func (w *LogWriter) LastSuccess() time.Time {
+	w.lastMutex.Lock()
+	defer w.lastMutex.Unlock()
+	return w.lastSuccess
+}
+
+// recordWrite records the outcome of writing n entries, counting them as
+// dropped if they are lost.
+func (w *LogWriter) recordWrite(n int, err error) {
+	w.lastMutex.Lock()
+	if err == nil {
+		w.lastSuccess = time.Now()
+	} else {
+		w.lastError = err
+	}
+	w.lastMutex.Unlock()
+	if writeLost(err) {
+		w.drops.writeFailed.Add(uint64(n))
+	}
+}
+
+// writeLost reports whether the entries of a write that returned err are
+// lost. Entries of rate-limited writes are kept for later, and those of
+// partial writes are resubmitted.
+func writeLost(err error) bool {
+	if err == nil || rejectedLines(err) != nil {
+		return false
+	}
+	_, limited := rateLimited(err)
+	return !limited
+}
+
+// BufferLen returns the number of entries waiting in the buffer, including
+// those of a flush in progress. It is zero for unbuffered writers.
+func (w *LogWriter) BufferLen() int {
+	return int(w.bufferLen.Load())
+}
+
+// BufferCap returns the number of entries the buffer holds before logging
+// has to wait for a flush. It is zero for unbuffered writers.
+func (w *LogWriter) BufferCap() int {
+	if !w.buffered() {
+		return 0
+	}
+	return w.bufferCap
+}
+
+// storeBufferLen updates the length reported by BufferLen, and queues a
+// watermark notification if the length crossed one. It must be called with
+// flushMutex held.
+func (w *LogWriter) storeBufferLen() {
+	n := w.batched
+	if w.batcher == nil {
+		n = w.buffer.Len()
+	}
+	w.bufferLen.Store(int64(n))
+	if w.watermarks == nil {
+		return
+	}
+	switch {
+	case !w.overHigh && n >= w.watermarkHigh:
+		w.overHigh = true
+	case w.overHigh && n <= w.watermarkLow:
+		w.overHigh = false
+	default:
+		return
+	}
+	select {
+	case w.watermarks <- watermarkEvent{over: w.overHigh, length: n}:
+	default:
+		w.handleError(errors.New("watermark notification is still pending, dropping it"))
+	}
+}
+
+// watermarkEvent is a buffer watermark crossing waiting to be notified.
+type watermarkEvent struct {
+	over   bool
+	length int
+}
+
+// runWatermarks notifies watermark crossings until the writer is closed.
+// Notifications run outside of flushMutex, so they may log themselves.
+func (w *LogWriter) runWatermarks() {
+	for {
+		select {
+		case <-w.done:
+			return
+		case event := <-w.watermarks:
+			w.notifyWatermark(event)
+		}
+	}
+}
+
+func (w *LogWriter) notifyWatermark(event watermarkEvent) {
+	defer func() {
+		if r := recover(); r != nil {
+			w.handleError(fmt.Errorf("recovered from panic in watermark notification: %v", r))
+		}
+	}()
+	w.watermarkNotify(event.over, event.length)
+}
@@ -0,0 +1,162 @@
+package influxlogger
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/hadi77ir/go-logging"
+)
+
+func TestBufferLen(t *testing.T) {
+	writer := newTestWriter(t, "", 10, WithBackend(&recordingBackend{}))
+	if got := writer.BufferCap(); got != 10 {
+		t.Errorf("got buffer capacity %d, want 10", got)
+	}
+	writeMessages(t, writer, "a", "b", "c")
+	if got := writer.BufferLen(); got != 3 {
+		t.Errorf("got buffer length %d, want 3", got)
+	}
+	if err := writer.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := writer.BufferLen(); got != 0 {
+		t.Errorf("got buffer length %d after the flush, want 0", got)
+	}
+	unbuffered := newTestWriter(t, "", 0, WithBackend(&recordingBackend{}))
+	writeMessages(t, unbuffered, "a")
+	if unbuffered.BufferLen() != 0 || unbuffered.BufferCap() != 0 {
+		t.Errorf("got buffer length %d and capacity %d unbuffered, want 0", unbuffered.BufferLen(), unbuffered.BufferCap())
+	}
+}
+
+func TestBufferWatermarks(t *testing.T) {
+	type event struct {
+		over   bool
+		length int
+	}
+	events := make(chan event, 10)
+	writer := newTestWriter(t, "", 10, WithBackend(&recordingBackend{}),
+		WithBufferWatermarks(3, 1, func(over bool, length int) {
+			events <- event{over, length}
+		}))
+	next := func() event {
+		t.Helper()
+		select {
+		case e := <-events:
+			return e
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for a watermark notification")
+			return event{}
+		}
+	}
+	writeMessages(t, writer, "a", "b")
+	writeMessages(t, writer, "c", "d")
+	if e := next(); e != (event{true, 3}) {
+		t.Errorf("got %+v, want the high watermark crossed at 3", e)
+	}
+	if err := writer.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if e := next(); e != (event{false, 0}) {
+		t.Errorf("got %+v, want the low watermark crossed at 0", e)
+	}
+	select {
+	case e := <-events:
+		t.Errorf("got unexpected notification %+v", e)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	tests := []struct {
+		name      string
+		high, low int
+		notify    func(bool, int)
+	}{
+		{"negative low", 3, -1, func(bool, int) {}},
+		{"high not above low", 3, 3, func(bool, int) {}},
+		{"nil notify", 3, 1, nil},
+	}
+	for _, tt := range tests {
+		if err := optionsError(WithBufferWatermarks(tt.high, tt.low, tt.notify)); err == nil {
+			t.Errorf("expected %s to be rejected", tt.name)
+		}
+	}
+}
+
+func TestStats(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		body        string
+		bufferLimit int
+		want        Stats
+	}{
+		{"rate limited", http.StatusTooManyRequests, "", 0, Stats{RateLimited: 1}},
+		{"rejected", http.StatusBadRequest, `{"error":"partial write of line protocol occurred","data":[` +
+			`{"original_line":"","line_number":1,"error_message":"invalid column type"}]}`, 0, Stats{Rejected: 1}},
+		{"write failed", http.StatusInternalServerError, "", 0, Stats{WriteFailed: 1}},
+		{"buffer full", http.StatusNoContent, "", 2, Stats{BufferFull: 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTestServer(t, func(rw http.ResponseWriter, r request, n int) {
+				if n > 1 {
+					rw.WriteHeader(http.StatusNoContent)
+					return
+				}
+				rw.WriteHeader(tt.status)
+				_, _ = io.WriteString(rw, tt.body)
+			})
+			writer := newTestWriter(t, "lp+"+server.URL+"/write", tt.bufferLimit)
+			if tt.bufferLimit > 0 {
+				// the buffer can't be flushed to make room during a pause
+				writer.pause(time.Hour)
+			}
+			// one more entry than the buffer holds
+			for range tt.bufferLimit + 1 {
+				_ = writer.Write(logging.InfoLevel, []any{"hello"}, nil)
+			}
+			if got := writer.Stats(); got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLastWrite(t *testing.T) {
+	server := newTestServer(t, func(rw http.ResponseWriter, r request, n int) {
+		if n == 2 {
+			rw.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		rw.WriteHeader(http.StatusNoContent)
+	})
+	writer := newTestWriter(t, "lp+"+server.URL+"/write", 0)
+	if writer.LastError() != nil || !writer.LastSuccess().IsZero() {
+		t.Errorf("got last error %v and success %v before any write", writer.LastError(), writer.LastSuccess())
+	}
+	start := time.Now()
+	writeMessages(t, writer, "a")
+	success := writer.LastSuccess()
+	if success.Before(start) || writer.LastError() != nil {
+		t.Errorf("got last success %v and error %v, want a success after %v", success, writer.LastError(), start)
+	}
+	if err := writer.Write(logging.InfoLevel, []any{"b"}, nil); err == nil {
+		t.Fatal("expected the second write to fail")
+	}
+	var writeErr *WriteError
+	if !errors.As(writer.LastError(), &writeErr) || writeErr.StatusCode != http.StatusInternalServerError {
+		t.Errorf("got last error %v, want the failed write", writer.LastError())
+	}
+	if !writer.LastSuccess().Equal(success) {
+		t.Error("expected a failed write to leave the last success alone")
+	}
+	// the last error isn't cleared by a later success
+	writeMessages(t, writer, "c")
+	if writer.LastError() == nil || writer.LastSuccess().Before(success) {
+		t.Errorf("got last error %v and success %v", writer.LastError(), writer.LastSuccess())
+	}
+}
@@ -0,0 +1,43 @@
+package influxlogger
+
+import (
+	"github.com/hadi77ir/go-logging"
+)
+
+// commonTags returns the tags every level shares with the same value.
+func commonTags(tags map[logging.Level]map[string]string) map[string]string {
+	var common map[string]string
+	for _, levelTags := range tags {
+		if common == nil {
+			common = make(map[string]string, len(levelTags))
+			for key, value := range levelTags {
+				if value != "" {
+					common[key] = value
+				}
+			}
+			continue
+		}
+		for key, value := range common {
+			if levelTags[key] != value {
+				delete(common, key)
+			}
+		}
+	}
+	return common
+}
+
+// withoutTags returns tags of every level, leaving out those in exclude and
+// those without a value, which points can't carry.
+func withoutTags(tags map[logging.Level]map[string]string, exclude map[string]string) map[logging.Level]map[string]string {
+	result := make(map[logging.Level]map[string]string, len(tags))
+	for level, levelTags := range tags {
+		remaining := make(map[string]string, len(levelTags))
+		for key, value := range levelTags {
+			if _, ok := exclude[key]; !ok && value != "" {
+				remaining[key] = value
+			}
+		}
+		result[level] = remaining
+	}
+	return result
+}
@@ -0,0 +1,61 @@
+package influxlogger
+
+import (
+	"maps"
+	"reflect"
+	"testing"
+
+	"github.com/hadi77ir/go-logging"
+)
+
+func TestCommonTags(t *testing.T) {
+	tags := map[logging.Level]map[string]string{
+		logging.InfoLevel:  {"appname": "test", "host": "a", "severity": "info", "empty": ""},
+		logging.ErrorLevel: {"appname": "test", "host": "a", "severity": "err", "empty": ""},
+		logging.DebugLevel: {"appname": "test", "host": "a", "severity": "debug", "empty": ""},
+	}
+	common := commonTags(tags)
+	if want := map[string]string{"appname": "test", "host": "a"}; !maps.Equal(common, want) {
+		t.Errorf("got common tags %v, want %v", common, want)
+	}
+	for level, levelTags := range withoutTags(tags, common) {
+		// empty tags are left out, as points can't carry them
+		if want := map[string]string{"severity": tags[level]["severity"]}; !maps.Equal(levelTags, want) {
+			t.Errorf("got %v tags %v, want %v", level, levelTags, want)
+		}
+	}
+}
+
+func TestDefaultTags(t *testing.T) {
+	writer := newTestWriter(t, "", 0, WithBackend(&recordingBackend{}), WithFacility("daemon", 3))
+	want := map[string]string{"appname": "test", "host": "localhost", "hostname": "localhost", "facility": "daemon"}
+	if !maps.Equal(writer.defaultTags, want) {
+		t.Errorf("got default tags %v, want %v", writer.defaultTags, want)
+	}
+	for level, tags := range writer.pointTags {
+		if !maps.Equal(tags, map[string]string{"severity": writer.tags[level]["severity"]}) {
+			t.Errorf("got %v point tags %v, want only the severity", level, tags)
+		}
+	}
+	// points leave default tags to the client, lines carry every tag
+	l := writtenLine(t, logging.WarnLevel, "hello", nil, WithFacility("daemon", 3))
+	want["severity"] = "warn"
+	if !maps.Equal(l.tags, want) {
+		t.Errorf("got line tags %v, want %v", l.tags, want)
+	}
+}
+
+func TestPointTagsShared(t *testing.T) {
+	writer := newTestWriter(t, "", 0, WithBackend(&recordingBackend{}))
+	first := writer.newPoint(&Entry{Level: logging.InfoLevel, Message: "a"})
+	second := writer.newPoint(&Entry{Level: logging.InfoLevel, Message: "b"})
+	if reflect.ValueOf(first.Values.Tags).UnsafePointer() != reflect.ValueOf(second.Values.Tags).UnsafePointer() {
+		t.Error("expected points of the same level to share their tags")
+	}
+	if !maps.Equal(first.Values.Tags, writer.pointTags[logging.InfoLevel]) {
+		t.Errorf("got point tags %v, want %v", first.Values.Tags, writer.pointTags[logging.InfoLevel])
+	}
+	if first.Values.Fields["message"] != "a" || second.Values.Fields["message"] != "b" {
+		t.Errorf("got fields %v and %v", first.Values.Fields, second.Values.Fields)
+	}
+}
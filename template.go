@@ -0,0 +1,34 @@
+package influxlogger
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hadi77ir/go-logging"
+)
+
+// renderTemplate replaces every {name} in template with the value of the
+// field name. Placeholders without a matching field are kept as they are.
+func renderTemplate(template string, fields logging.Fields) string {
+	var b strings.Builder
+	for {
+		start := strings.IndexByte(template, '{')
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(template[start:], '}')
+		if end < 0 {
+			break
+		}
+		end += start
+		b.WriteString(template[:start])
+		if value, ok := fields[template[start+1:end]]; ok {
+			fmt.Fprint(&b, value)
+		} else {
+			b.WriteString(template[start : end+1])
+		}
+		template = template[end+1:]
+	}
+	b.WriteString(template)
+	return b.String()
+}
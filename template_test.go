@@ -0,0 +1,51 @@
+package influxlogger
+
+import (
+	"testing"
+
+	"github.com/hadi77ir/go-logging"
+)
+
+func TestRenderTemplate(t *testing.T) {
+	fields := logging.Fields{"user": "alice", "count": 3, "empty": ""}
+	tests := []struct {
+		template, want string
+	}{
+		{"no placeholders", "no placeholders"},
+		{"hello {user}", "hello alice"},
+		{"{user} has {count} items", "alice has 3 items"},
+		{"{user}{user}", "alicealice"},
+		{"missing {other} kept", "missing {other} kept"},
+		{"empty [{empty}]", "empty []"},
+		{"unclosed {user", "unclosed {user"},
+		{"stray } and {user}", "stray } and alice"},
+		{"{}", "{}"},
+	}
+	for _, tt := range tests {
+		if got := renderTemplate(tt.template, fields); got != tt.want {
+			t.Errorf("renderTemplate(%q) = %q, want %q", tt.template, got, tt.want)
+		}
+	}
+}
+
+func TestLogT(t *testing.T) {
+	lines := writtenLines(t, func(w *LogWriter) {
+		logger := &Logger{writer: w, fields: logging.Fields{"service": "api"}}
+		logger.LogT(logging.InfoLevel, "{service} served {path}", logging.Fields{"path": "/login"})
+	})
+	if len(lines) != 1 {
+		t.Fatalf("got %d lines, want 1", len(lines))
+	}
+	l := lines[0]
+	if l.fields["message"] != "api served /login" || l.fields["template"] != "{service} served {path}" {
+		t.Errorf("got message %#v and template %#v", l.fields["message"], l.fields["template"])
+	}
+	if l.fields["fields.service"] != "api" || l.fields["fields.path"] != "/login" {
+		t.Errorf("got fields %v", l.fields)
+	}
+	// entries without a template have no template field
+	l = writtenLine(t, logging.InfoLevel, "hello", nil)
+	if _, ok := l.fields["template"]; ok {
+		t.Errorf("got a template field %#v", l.fields["template"])
+	}
+}
@@ -0,0 +1,32 @@
+package influxlogger
+
+import "time"
+
+// TimestampFormat renders the time of an entry as the value of its
+// timestamp field.
+type TimestampFormat func(t time.Time) any
+
+var (
+	// TimestampRFC3339 formats the time as an RFC 3339 string in UTC with
+	// second precision. It is the default.
+	TimestampRFC3339 TimestampFormat = TimestampLayout(time.RFC3339)
+	// TimestampRFC3339Nano formats the time as an RFC 3339 string in UTC
+	// with nanosecond precision.
+	TimestampRFC3339Nano TimestampFormat = TimestampLayout(time.RFC3339Nano)
+	// TimestampUnix stores the time as an integer of seconds since the epoch.
+	TimestampUnix TimestampFormat = func(t time.Time) any { return t.Unix() }
+	// TimestampUnixMilli stores the time as an integer of milliseconds since
+	// the epoch.
+	TimestampUnixMilli TimestampFormat = func(t time.Time) any { return t.UnixMilli() }
+	// TimestampUnixNano stores the time as an integer of nanoseconds since
+	// the epoch.
+	TimestampUnixNano TimestampFormat = func(t time.Time) any { return t.UnixNano() }
+)
+
+// TimestampLayout formats the time in UTC using layout, as accepted by
+// time.Time.Format.
+func TimestampLayout(layout string) TimestampFormat {
+	return func(t time.Time) any {
+		return t.UTC().Format(layout)
+	}
+}
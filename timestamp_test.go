@@ -0,0 +1,48 @@
+package influxlogger
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hadi77ir/go-logging"
+)
+
+func TestTimestampFormat(t *testing.T) {
+	at := time.Date(2025, 6, 1, 12, 30, 45, 123456789, time.FixedZone("CEST", 2*60*60))
+	tests := []struct {
+		name   string
+		format TimestampFormat
+		want   any
+	}{
+		{"rfc3339", TimestampRFC3339, "2025-06-01T10:30:45Z"},
+		{"rfc3339 nano", TimestampRFC3339Nano, "2025-06-01T10:30:45.123456789Z"},
+		{"unix", TimestampUnix, int64(1748773845)},
+		{"unix milli", TimestampUnixMilli, int64(1748773845123)},
+		{"unix nano", TimestampUnixNano, int64(1748773845123456789)},
+		{"layout", TimestampLayout(time.DateTime), "2025-06-01 10:30:45"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.format(at); got != tt.want {
+				t.Errorf("got %#v, want %#v", got, tt.want)
+			}
+			l := writtenLine(t, logging.InfoLevel, "hello", nil, WithTimestampFormat(tt.format))
+			if got, want := l.fields["timestamp"], tt.format(l.time); got != want {
+				t.Errorf("wrote timestamp %#v, want %#v", got, want)
+			}
+		})
+	}
+	if err := optionsError(WithTimestampFormat(nil)); err == nil {
+		t.Error("expected a nil format to be rejected")
+	}
+}
+
+func TestWithoutTimestampField(t *testing.T) {
+	l := writtenLine(t, logging.InfoLevel, "hello", nil, WithoutTimestampField())
+	if _, ok := l.fields["timestamp"]; ok {
+		t.Errorf("got a timestamp field %v", l.fields["timestamp"])
+	}
+	if l.fields["message"] != "hello" || l.time.IsZero() {
+		t.Errorf("got %+v", l)
+	}
+}
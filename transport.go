@@ -0,0 +1,173 @@
+package influxlogger
+
+import (
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// transportOptions holds the settings applied to the HTTP requests of the
+// client and the HTTP based backends.
+type transportOptions struct {
+	header http.Header
+	// refreshInterval and refreshFailures control when pooled connections
+	// are dropped, so the next request resolves and dials the server again
+	refreshInterval time.Duration
+	refreshFailures int
+	config          TransportConfig
+	// proxy overrides the proxy taken from the environment
+	proxy     *url.URL
+	tlsConfig *tls.Config
+}
+
+// TransportConfig tunes the connections writes are sent over. Zero values
+// keep the defaults of http.DefaultTransport.
+type TransportConfig struct {
+	// DialTimeout bounds the time taken to connect to the server
+	DialTimeout time.Duration
+	// KeepAlive is the interval of TCP keep-alive probes, which are
+	// disabled if it is negative
+	KeepAlive time.Duration
+	// DisableKeepAlives opens a new connection for every request
+	DisableKeepAlives   bool
+	MaxIdleConns        int
+	MaxIdleConnsPerHost int
+	// IdleConnTimeout is how long an idle connection is kept for reuse
+	IdleConnTimeout time.Duration
+}
+
+func (c TransportConfig) validate() error {
+	if c.DialTimeout < 0 || c.MaxIdleConns < 0 || c.MaxIdleConnsPerHost < 0 || c.IdleConnTimeout < 0 {
+		return errors.New("invalid transport config")
+	}
+	return nil
+}
+
+// parseProxyURL parses the URL of a proxy, which needs a scheme and a host.
+func parseProxyURL(proxyURL string) (*url.URL, error) {
+	u, err := url.Parse(proxyURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return nil, errors.New("invalid proxy url")
+	}
+	return u, nil
+}
+
+// defaultDialer matches the dialer of http.DefaultTransport.
+var defaultDialer = net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+
+// dialer returns the dialer connecting to the server.
+func (o *transportOptions) dialer() *net.Dialer {
+	dialer := defaultDialer
+	if o.config.DialTimeout > 0 {
+		dialer.Timeout = o.config.DialTimeout
+	}
+	if o.config.KeepAlive != 0 {
+		dialer.KeepAlive = o.config.KeepAlive
+	}
+	return &dialer
+}
+
+// newTransport returns the transport connecting to the server. Like
+// http.DefaultTransport, it goes through the proxy set by the HTTP_PROXY,
+// HTTPS_PROXY and NO_PROXY environment variables, unless a proxy is given.
+func (o *transportOptions) newTransport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if o.proxy != nil {
+		transport.Proxy = http.ProxyURL(o.proxy)
+	}
+	if o.tlsConfig != nil {
+		transport.TLSClientConfig = o.tlsConfig.Clone()
+	}
+	transport.DialContext = o.dialer().DialContext
+	transport.DisableKeepAlives = o.config.DisableKeepAlives
+	if o.config.MaxIdleConns > 0 {
+		transport.MaxIdleConns = o.config.MaxIdleConns
+	}
+	if o.config.MaxIdleConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = o.config.MaxIdleConnsPerHost
+	}
+	if o.config.IdleConnTimeout > 0 {
+		transport.IdleConnTimeout = o.config.IdleConnTimeout
+	}
+	return transport
+}
+
+// roundTripper wraps base to apply the per-request settings.
+func (o *transportOptions) roundTripper(base *http.Transport) http.RoundTripper {
+	var rt http.RoundTripper = base
+	if o.refreshInterval > 0 || o.refreshFailures > 0 {
+		rt = &refreshTransport{
+			base:        base,
+			interval:    o.refreshInterval,
+			maxFailures: o.refreshFailures,
+			refreshed:   time.Now(),
+		}
+	}
+	if len(o.header) == 0 {
+		return rt
+	}
+	return &headerTransport{base: rt, header: o.header}
+}
+
+// headerTransport adds a fixed set of headers to every request.
+type headerTransport struct {
+	base   http.RoundTripper
+	header http.Header
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// a RoundTripper must not modify the request it was given
+	req = req.Clone(req.Context())
+	for key, values := range t.header {
+		req.Header[key] = values
+	}
+	return t.base.RoundTrip(req)
+}
+
+// refreshTransport closes the idle connections of base every interval, and
+// after maxFailures requests in a row failed or got a server error, so that
+// a server that moved is found again instead of being reached through a
+// pinned connection.
+type refreshTransport struct {
+	base        *http.Transport
+	interval    time.Duration
+	maxFailures int
+	mutex       sync.Mutex
+	refreshed   time.Time
+	failures    int
+}
+
+func (t *refreshTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.mutex.Lock()
+	if t.interval > 0 && time.Since(t.refreshed) >= t.interval {
+		t.refresh()
+	}
+	t.mutex.Unlock()
+	resp, err := t.base.RoundTrip(req)
+	failed := err != nil || resp.StatusCode >= http.StatusInternalServerError
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if !failed {
+		t.failures = 0
+		return resp, err
+	}
+	t.failures++
+	if t.maxFailures > 0 && t.failures >= t.maxFailures {
+		t.refresh()
+	}
+	return resp, err
+}
+
+// refresh must be called with mutex held.
+func (t *refreshTransport) refresh() {
+	t.base.CloseIdleConnections()
+	t.refreshed = time.Now()
+	t.failures = 0
+}
@@ -0,0 +1,193 @@
+package influxlogger
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hadi77ir/go-logging"
+)
+
+func TestWithHeaders(t *testing.T) {
+	tests := []struct {
+		name string
+		// connection is appended to the scheme and address of the server
+		scheme, query string
+	}{
+		{"line protocol", "lp+", "/write"},
+		{"influxdb1", "influxdb1+", "?db=logs"},
+		{"influxdb2", "influxdb2+", "?org=acme&bucket=logs"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTestServer(t, func(rw http.ResponseWriter, r request, n int) {
+				rw.WriteHeader(http.StatusNoContent)
+			})
+			header := http.Header{"X-Scope-Orgid": {"tenant"}, "Traceparent": {"00-1-2-01"}}
+			writer := newTestWriter(t, tt.scheme+server.URL+tt.query, 0, WithHeaders(header))
+			// the option keeps a copy of the headers
+			header.Set("X-Scope-Orgid", "other")
+			writeMessages(t, writer, "hello")
+			requests := server.received()
+			if len(requests) != 1 {
+				t.Fatalf("got %d requests, want 1", len(requests))
+			}
+			if got := requests[0].header.Get("X-Scope-Orgid"); got != "tenant" {
+				t.Errorf("got X-Scope-OrgID %q, want tenant", got)
+			}
+			if got := requests[0].header.Get("Traceparent"); got != "00-1-2-01" {
+				t.Errorf("got traceparent %q", got)
+			}
+		})
+	}
+}
+
+// connServer answers writes with the status of respond, and a body for
+// errors, counting the connections it accepts.
+func connServer(t *testing.T, respond func(n int) int) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var conns, requests atomic.Int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		status := respond(int(requests.Add(1)))
+		rw.WriteHeader(status)
+		if status >= http.StatusBadRequest {
+			_, _ = io.WriteString(rw, `{"error":"server error"}`)
+		}
+	}))
+	server.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			conns.Add(1)
+		}
+	}
+	server.Start()
+	t.Cleanup(server.Close)
+	return server, &conns
+}
+
+func TestWithConnectionRefresh(t *testing.T) {
+	noContent := func(n int) int { return http.StatusNoContent }
+	tests := []struct {
+		name    string
+		opts    []Option
+		respond func(n int) int
+		pause   time.Duration
+		conns   int32
+	}{
+		{"pooled", nil, noContent, 0, 1},
+		{"interval", []Option{WithConnectionRefresh(time.Millisecond, 0)}, noContent, 5 * time.Millisecond, 4},
+		{"failures", []Option{WithConnectionRefresh(0, 2)}, func(n int) int {
+			if n <= 2 {
+				return http.StatusInternalServerError
+			}
+			return http.StatusNoContent
+		}, 0, 2},
+		{"failures reset by a success", []Option{WithConnectionRefresh(0, 2)}, func(n int) int {
+			if n%2 == 1 {
+				return http.StatusInternalServerError
+			}
+			return http.StatusNoContent
+		}, 0, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, conns := connServer(t, tt.respond)
+			writer := newTestWriter(t, "lp+"+server.URL+"/write", 0, tt.opts...)
+			for range 4 {
+				time.Sleep(tt.pause)
+				_ = writer.Write(logging.InfoLevel, []any{"hello"}, nil)
+			}
+			if got := conns.Load(); got != tt.conns {
+				t.Errorf("got %d connections, want %d", got, tt.conns)
+			}
+		})
+	}
+	if err := optionsError(WithConnectionRefresh(-time.Second, 0)); err == nil {
+		t.Error("expected a negative interval to be rejected")
+	}
+	if err := optionsError(WithConnectionRefresh(0, -1)); err == nil {
+		t.Error("expected a negative number of failures to be rejected")
+	}
+}
+
+func TestWithTransportConfig(t *testing.T) {
+	config := TransportConfig{
+		DialTimeout:         time.Second,
+		KeepAlive:           -1,
+		MaxIdleConns:        7,
+		MaxIdleConnsPerHost: 3,
+		IdleConnTimeout:     time.Minute,
+	}
+	options := &transportOptions{config: config}
+	transport := options.newTransport()
+	if transport.MaxIdleConns != 7 || transport.MaxIdleConnsPerHost != 3 || transport.IdleConnTimeout != time.Minute {
+		t.Errorf("got max idle %d, per host %d and idle timeout %v",
+			transport.MaxIdleConns, transport.MaxIdleConnsPerHost, transport.IdleConnTimeout)
+	}
+	if dialer := options.dialer(); dialer.Timeout != time.Second || dialer.KeepAlive != -1 {
+		t.Errorf("got dial timeout %v and keep-alive %v", dialer.Timeout, dialer.KeepAlive)
+	}
+	// zero values keep the defaults
+	defaults := http.DefaultTransport.(*http.Transport)
+	transport = (&transportOptions{}).newTransport()
+	if transport.MaxIdleConns != defaults.MaxIdleConns || transport.IdleConnTimeout != defaults.IdleConnTimeout {
+		t.Errorf("got max idle %d and idle timeout %v", transport.MaxIdleConns, transport.IdleConnTimeout)
+	}
+	if dialer := (&transportOptions{}).dialer(); dialer.Timeout != defaultDialer.Timeout || dialer.KeepAlive != defaultDialer.KeepAlive {
+		t.Errorf("got dial timeout %v and keep-alive %v", dialer.Timeout, dialer.KeepAlive)
+	}
+
+	server, conns := connServer(t, func(n int) int { return http.StatusNoContent })
+	writer := newTestWriter(t, "lp+"+server.URL+"/write", 0, WithTransportConfig(TransportConfig{DisableKeepAlives: true}))
+	writeMessages(t, writer, "a", "b", "c")
+	if got := conns.Load(); got != 3 {
+		t.Errorf("got %d connections without keep-alives, want 3", got)
+	}
+
+	for _, invalid := range []TransportConfig{
+		{DialTimeout: -1},
+		{MaxIdleConns: -1},
+		{MaxIdleConnsPerHost: -1},
+		{IdleConnTimeout: -1},
+	} {
+		if err := optionsError(WithTransportConfig(invalid)); err == nil {
+			t.Errorf("expected %+v to be rejected", invalid)
+		}
+	}
+}
+
+func TestWithProxy(t *testing.T) {
+	tests := []struct {
+		name       string
+		connection string
+		path       string
+	}{
+		{"line protocol", "lp+http://influxdb.invalid/write", "/write"},
+		{"influxdb1", "influxdb1+http://influxdb.invalid?db=logs", "/write"},
+		{"influxdb2", "influxdb2+http://influxdb.invalid?org=acme&bucket=logs", "/api/v2/write"},
+		{"influxdb3", "http://influxdb.invalid?token=secret&database=logs", "/api/v2/write"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// a proxy sees the absolute URL of the request
+			proxy := newTestServer(t, func(rw http.ResponseWriter, r request, n int) {
+				rw.WriteHeader(http.StatusNoContent)
+			})
+			writer := newTestWriter(t, tt.connection, 0, WithProxy(proxy.URL))
+			writeMessages(t, writer, "hello")
+			requests := writes(proxy)
+			if len(requests) != 1 || requests[0].path != tt.path {
+				t.Errorf("got %+v, want a write to %s through the proxy", requests, tt.path)
+			}
+		})
+	}
+	for _, proxyURL := range []string{"", "proxy.internal:3128", "http://", "://proxy"} {
+		if err := optionsError(WithProxy(proxyURL)); err == nil {
+			t.Errorf("expected %q to be rejected", proxyURL)
+		}
+	}
+}
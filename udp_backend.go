@@ -0,0 +1,98 @@
+package influxlogger
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"net/url"
+	"strconv"
+	"sync"
+)
+
+// defaultUDPPayloadSize keeps datagrams within a 1500 byte Ethernet MTU
+// once IP and UDP headers are added.
+const defaultUDPPayloadSize = 1400
+
+// udpBackend sends line protocol as fire-and-forget datagrams, packing as
+// many whole lines into each datagram as fit in the payload size.
+type udpBackend struct {
+	address     string
+	payloadSize int
+	mutex       sync.Mutex
+	conn        net.Conn
+}
+
+// newUDPBackend creates a UDP backend from a connection string like
+//
+//	udp://localhost:8089?payloadSize=1400
+func newUDPBackend(connection string) (*udpBackend, error) {
+	u, err := url.Parse(connection)
+	if err != nil {
+		return nil, err
+	}
+	if u.Host == "" {
+		return nil, errors.New("missing udp address")
+	}
+	b := &udpBackend{address: u.Host, payloadSize: defaultUDPPayloadSize}
+	if size := u.Query().Get("payloadSize"); size != "" {
+		b.payloadSize, err = strconv.Atoi(size)
+		if err != nil || b.payloadSize <= 0 {
+			return nil, errors.New("invalid udp payload size")
+		}
+	}
+	return b, nil
+}
+
+func (b *udpBackend) WriteLineProtocol(ctx context.Context, lines []byte) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.conn == nil {
+		var dialer net.Dialer
+		conn, err := dialer.DialContext(ctx, "udp", b.address)
+		if err != nil {
+			return err
+		}
+		b.conn = conn
+	}
+	var errs []error
+	for len(lines) > 0 {
+		n := b.nextDatagram(lines)
+		if _, err := b.conn.Write(lines[:n]); err != nil {
+			errs = append(errs, err)
+		}
+		lines = lines[n:]
+	}
+	return errors.Join(errs...)
+}
+
+// nextDatagram returns the length of the longest run of whole lines at the
+// start of lines that fits in a datagram. A single line longer than the
+// payload size is sent on its own and left to IP fragmentation.
+func (b *udpBackend) nextDatagram(lines []byte) int {
+	n := 0
+	for n < len(lines) {
+		end := bytes.IndexByte(lines[n:], '\n')
+		if end < 0 {
+			end = len(lines) - n
+		} else {
+			end++
+		}
+		if n > 0 && n+end > b.payloadSize {
+			break
+		}
+		n += end
+	}
+	return n
+}
+
+func (b *udpBackend) Close() error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.conn == nil {
+		return nil
+	}
+	err := b.conn.Close()
+	b.conn = nil
+	return err
+}
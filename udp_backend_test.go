@@ -0,0 +1,74 @@
+package influxlogger
+
+import (
+	"context"
+	"net"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUDPPacking(t *testing.T) {
+	tests := []struct {
+		name        string
+		payloadSize int
+		lines       string
+		datagrams   []string
+	}{
+		{"one datagram", 100, "a 1\nb 2\n", []string{"a 1\nb 2\n"}},
+		{"exact fit", 8, "a 1\nb 2\nc 3\n", []string{"a 1\nb 2\n", "c 3\n"}},
+		{"one line each", 5, "a 1\nb 2\n", []string{"a 1\n", "b 2\n"}},
+		{"oversized line", 4, "a 1\nlonger line\nb 2\n", []string{"a 1\n", "longer line\n", "b 2\n"}},
+		{"no trailing newline", 100, "a 1\nb 2", []string{"a 1\nb 2"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &udpBackend{payloadSize: tt.payloadSize}
+			var datagrams []string
+			for lines := []byte(tt.lines); len(lines) > 0; {
+				n := b.nextDatagram(lines)
+				datagrams = append(datagrams, string(lines[:n]))
+				lines = lines[n:]
+			}
+			if !slices.Equal(datagrams, tt.datagrams) {
+				t.Errorf("got %q, want %q", datagrams, tt.datagrams)
+			}
+		})
+	}
+}
+
+func TestUDPBackend(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	backend, err := newUDPBackend("udp://" + conn.LocalAddr().String() + "?payloadSize=40")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer backend.Close()
+	lines := strings.Repeat("logs message=\"hello\" 1\n", 3)
+	if err := backend.WriteLineProtocol(context.Background(), []byte(lines)); err != nil {
+		t.Fatal(err)
+	}
+	var received []string
+	buf := make([]byte, 1500)
+	for range 3 {
+		_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		received = append(received, string(buf[:n]))
+	}
+	if want := strings.SplitAfter(lines, "\n")[:3]; !slices.Equal(received, want) {
+		t.Errorf("got datagrams %q, want one line each", received)
+	}
+	for _, connection := range []string{"udp://", "udp://localhost:8089?payloadSize=0", "udp://localhost:8089?payloadSize=big"} {
+		if _, err := newUDPBackend(connection); err == nil {
+			t.Errorf("expected %s to be rejected", connection)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package influxlogger
+
+import (
+	"testing"
+
+	"github.com/hadi77ir/go-logging"
+)
+
+func TestUTF8Replacement(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+		want string
+	}{
+		{"default", nil, "�"},
+		{"replacement", []Option{WithUTF8Replacement("?")}, "?"},
+		{"removed", []Option{WithUTF8Replacement("")}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := &recordingBackend{}
+			writer, err := NewLogWriter("", "test", "host\xff", "1", 0, 0, append(tt.opts, WithBackend(backend))...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { _ = writer.Close() })
+			fields := logging.Fields{"ke\xffy": "va\xc3lue", "bytes": []byte("by\xfftes"), "valid": "héllo"}
+			// runs of invalid bytes are replaced once
+			if err := writer.Write(logging.InfoLevel, []any{"bad\xff\xfemessage"}, fields); err != nil {
+				t.Fatal(err)
+			}
+			lines := backend.written(t)
+			if len(lines) != 1 {
+				t.Fatalf("got %d lines, want 1", len(lines))
+			}
+			l := lines[0]
+			r := tt.want
+			if got, want := l.fields["message"], "bad"+r+"message"; got != want {
+				t.Errorf("got message %q, want %q", got, want)
+			}
+			if got, want := l.fields["fields.ke"+r+"y"], "va"+r+"lue"; got != want {
+				t.Errorf("got field %q, want %q in %v", got, want, l.fields)
+			}
+			// bytes that aren't text are encoded rather than replaced
+			if got := l.fields["fields.bytes"]; got != "Ynn/dGVz" {
+				t.Errorf("got bytes %q, want them base64 encoded", got)
+			}
+			if got := l.fields["fields.valid"]; got != "héllo" {
+				t.Errorf("got valid field %q", got)
+			}
+			if got, want := l.tags["host"], "host"+r; got != want {
+				t.Errorf("got host tag %q, want %q", got, want)
+			}
+		})
+	}
+	if err := optionsError(WithUTF8Replacement("\xff")); err == nil {
+		t.Error("expected an invalid replacement to be rejected")
+	}
+}